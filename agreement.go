@@ -0,0 +1,254 @@
+package frostclient
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HumanLabel is a class given to a camera image by an annotator.
+type HumanLabel struct {
+	CamID     int
+	Time      time.Time
+	Class     int
+	Annotator string
+	Path      string
+}
+
+// LabelTable names the table of human labels in the roadlabels database and
+// its columns. The time may be Unix seconds or RFC 3339 text.
+type LabelTable struct {
+	Table     string
+	CamID     string
+	Time      string
+	Class     string
+	Annotator string // may be NULL
+	Path      string // may be NULL
+}
+
+// RoadlabelsLabels is the label table read by HumanLabelsFromDB.
+var RoadlabelsLabels = LabelTable{Table: "labels", CamID: "cam_id", Time: "ts", Class: "class", Annotator: "annotator", Path: "path"}
+
+// HumanLabelsFromDB lists the human labels selected by f from
+// RoadlabelsLabels, sorted by camera and time.
+func HumanLabelsFromDB(conn *sql.DB, f CameraFilter) ([]HumanLabel, error) {
+	t := RoadlabelsLabels
+	rows, err := queryCameras(conn, t.Table, t.CamID, []string{t.CamID, t.Time, t.Class, t.Annotator, t.Path}, f)
+	if err != nil {
+		return nil, fmt.Errorf("query labels: %v", err)
+	}
+	defer rows.Close()
+
+	labels := []HumanLabel{}
+	for rows.Next() {
+		var l HumanLabel
+		var ts any
+		var annotator, path sql.NullString
+		if err := rows.Scan(&l.CamID, &ts, &l.Class, &annotator, &path); err != nil {
+			return labels, fmt.Errorf("scan label: %v", err)
+		}
+		l.Time, err = scanTime(ts)
+		if err != nil {
+			return labels, fmt.Errorf("label time %v of camera %d: %v", ts, l.CamID, err)
+		}
+		if !f.includes(l.Time) {
+			continue
+		}
+		l.Annotator = annotator.String
+		l.Path = path.String
+		labels = append(labels, l)
+	}
+	sort.SliceStable(labels, func(i, j int) bool {
+		if labels[i].CamID != labels[j].CamID {
+			return labels[i].CamID < labels[j].CamID
+		}
+		return labels[i].Time.Before(labels[j].Time)
+	})
+	return labels, rows.Err()
+}
+
+// AgreementOptions control CompareLabels. MapHuman translates the human
+// classes to the classes of the scheme the sensor labels are in, human
+// labels of classes not in it are left out. Without it the classes are
+// taken to be the same.
+type AgreementOptions struct {
+	Tolerance time.Duration
+	MapHuman  map[int]int
+}
+
+var DefaultAgreementOptions = AgreementOptions{
+	Tolerance: 5 * time.Minute,
+}
+
+// Counts is the number of compared labels and how many agreed.
+type Counts struct {
+	Total  int
+	Agreed int
+}
+
+func (c Counts) Rate() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Agreed) / float64(c.Total)
+}
+
+// Disagreement is a human label with the sensor observation disagreeing.
+type Disagreement struct {
+	Human  HumanLabel
+	Sensor ObsRoadweather
+}
+
+// Agreement compares human and sensor labels. Confusion counts
+// [human class][sensor class].
+type Agreement struct {
+	Scheme        Scheme
+	Confusion     map[int]map[int]int
+	Counts        Counts
+	Kappa         float64
+	PerStation    map[string]Counts
+	PerAnnotator  map[string]Counts
+	Disagreements []Disagreement
+	Unmatched     int // human labels without a sensor observation
+	Unmapped      int // human labels not in MapHuman
+}
+
+// CompareLabels pairs each human label with the sensor observation of its
+// camera nearest in time within opts.Tolerance, as MatchImages does, and
+// compares the classes.
+func CompareLabels(human []HumanLabel, obses []ObsRoadweather, scheme Scheme, opts AgreementOptions) Agreement {
+	a := Agreement{
+		Scheme:        scheme,
+		Confusion:     make(map[int]map[int]int),
+		PerStation:    make(map[string]Counts),
+		PerAnnotator:  make(map[string]Counts),
+		Disagreements: []Disagreement{},
+	}
+
+	byCam := obsByCam(obses)
+	for _, h := range human {
+		if opts.MapHuman != nil {
+			c, ok := opts.MapHuman[h.Class]
+			if !ok {
+				a.Unmapped++
+				continue
+			}
+			h.Class = c
+		}
+		img := Image{CamID: h.CamID, Time: h.Time, Path: h.Path}
+		p, ok := matchImage(byCam[h.CamID], img, ImageMatchOptions{Tolerance: opts.Tolerance})
+		if !ok {
+			a.Unmatched++
+			continue
+		}
+
+		if a.Confusion[h.Class] == nil {
+			a.Confusion[h.Class] = make(map[int]int)
+		}
+		a.Confusion[h.Class][p.Obs.Class]++
+
+		agreed := h.Class == p.Obs.Class
+		count := func(c Counts) Counts {
+			c.Total++
+			if agreed {
+				c.Agreed++
+			}
+			return c
+		}
+		a.Counts = count(a.Counts)
+		a.PerStation[p.Obs.Station] = count(a.PerStation[p.Obs.Station])
+		a.PerAnnotator[h.Annotator] = count(a.PerAnnotator[h.Annotator])
+		if !agreed {
+			a.Disagreements = append(a.Disagreements, Disagreement{Human: h, Sensor: p.Obs})
+		}
+	}
+	a.Kappa = CohensKappa(a.Confusion)
+	return a
+}
+
+// CohensKappa returns the agreement beyond chance of the confusion matrix
+// [rater 1][rater 2], 1 for complete agreement and 0 for chance.
+func CohensKappa(confusion map[int]map[int]int) float64 {
+	rows := make(map[int]int)
+	cols := make(map[int]int)
+	total, agreed := 0, 0
+	for r, row := range confusion {
+		for c, n := range row {
+			rows[r] += n
+			cols[c] += n
+			total += n
+			if r == c {
+				agreed += n
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	po := float64(agreed) / float64(total)
+	pe := 0.0
+	for k, n := range rows {
+		pe += float64(n) / float64(total) * float64(cols[k]) / float64(total)
+	}
+	if pe == 1 {
+		return 1 // One class only, which both raters agree on
+	}
+	return (po - pe) / (1 - pe)
+}
+
+func (a Agreement) classes() []int {
+	set := make(map[int]bool)
+	for c := range a.Scheme.Classes {
+		set[c] = true
+	}
+	for r, row := range a.Confusion {
+		set[r] = true
+		for c := range row {
+			set[c] = true
+		}
+	}
+	classes := make([]int, 0, len(set))
+	for c := range set {
+		classes = append(classes, c)
+	}
+	sort.Ints(classes)
+	return classes
+}
+
+func (a Agreement) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Agreement %s: %d of %d (%.1f%%), kappa %.3f, %d unmatched, %d unmapped\n",
+		a.Scheme.Name, a.Counts.Agreed, a.Counts.Total, 100*a.Counts.Rate(), a.Kappa, a.Unmatched, a.Unmapped)
+
+	classes := a.classes()
+	fmt.Fprintf(&b, "%12s", "human\\sensor")
+	for _, c := range classes {
+		fmt.Fprintf(&b, " %6d", c)
+	}
+	b.WriteString("\n")
+	for _, r := range classes {
+		fmt.Fprintf(&b, "%12d", r)
+		for _, c := range classes {
+			fmt.Fprintf(&b, " %6d", a.Confusion[r][c])
+		}
+		fmt.Fprintf(&b, "  %s\n", a.Scheme.Classes[r])
+	}
+
+	writeCounts := func(title string, counts map[string]Counts) {
+		keys := make([]string, 0, len(counts))
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(&b, "%s:\n", title)
+		for _, k := range keys {
+			c := counts[k]
+			fmt.Fprintf(&b, "  %-12s %5d of %5d (%.1f%%)\n", k, c.Agreed, c.Total, 100*c.Rate())
+		}
+	}
+	writeCounts("Per station", a.PerStation)
+	writeCounts("Per annotator", a.PerAnnotator)
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package frostclient
+
+import (
+	"database/sql"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCohensKappa(t *testing.T) {
+	tests := []struct {
+		name      string
+		confusion map[int]map[int]int
+		want      float64
+	}{
+		{"empty", map[int]map[int]int{}, 0},
+		{"complete agreement", map[int]map[int]int{0: {0: 10}, 1: {1: 5}}, 1},
+		{"one class", map[int]map[int]int{3: {3: 4}}, 1},
+		{"chance", map[int]map[int]int{0: {0: 1, 1: 1}, 1: {0: 1, 1: 1}}, 0},
+		{"worse than chance", map[int]map[int]int{0: {1: 5}, 1: {0: 5}}, -1},
+		// po 0.7, pe 0.5*0.6 + 0.5*0.4
+		{"partial", map[int]map[int]int{0: {0: 20, 1: 5}, 1: {0: 10, 1: 15}}, 0.4},
+	}
+	for _, tt := range tests {
+		if got := CohensKappa(tt.confusion); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: CohensKappa = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompareLabels(t *testing.T) {
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	obses := []ObsRoadweather{
+		{RefTime: at, Station: "SN1:0", CamID: 1, Class: DryE},
+		{RefTime: at.Add(time.Hour), Station: "SN1:0", CamID: 1, Class: IceE},
+		{RefTime: at, Station: "SN2:0", CamID: 2, Class: WetE},
+	}
+	human := []HumanLabel{
+		{CamID: 1, Time: at.Add(2 * time.Minute), Class: DryE, Annotator: "a"},
+		{CamID: 1, Time: at.Add(time.Hour), Class: SnowE, Annotator: "b", Path: "1/b.jpg"},
+		{CamID: 2, Time: at, Class: WetE, Annotator: "a"},
+		{CamID: 1, Time: at.Add(30 * time.Minute), Class: DryE, Annotator: "a"}, // beyond the tolerance
+		{CamID: 3, Time: at, Class: DryE, Annotator: "b"},                       // no sensor
+	}
+	a := CompareLabels(human, obses, Scheme8, DefaultAgreementOptions)
+	if a.Counts != (Counts{3, 2}) || a.Unmatched != 2 || a.Unmapped != 0 {
+		t.Errorf("counts %+v, %d unmatched, %d unmapped", a.Counts, a.Unmatched, a.Unmapped)
+	}
+	wantConfusion := map[int]map[int]int{DryE: {DryE: 1}, SnowE: {IceE: 1}, WetE: {WetE: 1}}
+	if !reflect.DeepEqual(a.Confusion, wantConfusion) {
+		t.Errorf("confusion %v, want %v", a.Confusion, wantConfusion)
+	}
+	if a.Kappa != CohensKappa(wantConfusion) {
+		t.Errorf("kappa %g", a.Kappa)
+	}
+	if want := map[string]Counts{"SN1:0": {2, 1}, "SN2:0": {1, 1}}; !reflect.DeepEqual(a.PerStation, want) {
+		t.Errorf("per station %v, want %v", a.PerStation, want)
+	}
+	if want := map[string]Counts{"a": {2, 2}, "b": {1, 0}}; !reflect.DeepEqual(a.PerAnnotator, want) {
+		t.Errorf("per annotator %v, want %v", a.PerAnnotator, want)
+	}
+	if want := []Disagreement{{human[1], obses[1]}}; !reflect.DeepEqual(a.Disagreements, want) {
+		t.Errorf("disagreements %+v, want %+v", a.Disagreements, want)
+	}
+	for _, line := range []string{"Agreement 8classes: 2 of 3 (66.7%)", "2 unmatched, 0 unmapped", "  b                0 of     1 (0.0%)"} {
+		if !strings.Contains(a.String(), line) {
+			t.Errorf("no %q in\n%s", line, a)
+		}
+	}
+
+	// Human classes mapped to the sensor scheme, others left out
+	opts := DefaultAgreementOptions
+	opts.MapHuman = map[int]int{10: DryE, 11: WetE}
+	human = []HumanLabel{
+		{CamID: 1, Time: at, Class: 10},
+		{CamID: 2, Time: at, Class: 10},
+		{CamID: 2, Time: at, Class: 12},
+	}
+	a = CompareLabels(human, obses, Scheme8, opts)
+	if a.Counts != (Counts{2, 1}) || a.Unmapped != 1 || a.Confusion[DryE][WetE] != 1 || a.Disagreements[0].Human.Class != DryE {
+		t.Errorf("mapped %+v", a)
+	}
+}
+
+func TestHumanLabelsFromDB(t *testing.T) {
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "roadlabels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE labels (cam_id INTEGER, ts, class INTEGER, annotator TEXT, path TEXT);
+		INSERT INTO labels VALUES
+			(2, 1676034000, 3, 'a', '/img/2/b.jpg'),
+			(2, '2023-02-10T12:00:00Z', 0, NULL, NULL),
+			(1, 1676030400, 1, 'b', '/img/1/a.jpg')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter CameraFilter
+		want   []HumanLabel
+	}{
+		{"all", CameraFilter{}, []HumanLabel{
+			{CamID: 1, Time: at, Class: 1, Annotator: "b", Path: "/img/1/a.jpg"},
+			{CamID: 2, Time: at, Class: 0},
+			{CamID: 2, Time: at.Add(time.Hour), Class: 3, Annotator: "a", Path: "/img/2/b.jpg"},
+		}},
+		{"camera and period", CameraFilter{CamIDs: []int{2}, From: at.Add(time.Minute), To: at.Add(2 * time.Hour)}, []HumanLabel{
+			{CamID: 2, Time: at.Add(time.Hour), Class: 3, Annotator: "a", Path: "/img/2/b.jpg"},
+		}},
+		{"no camera", CameraFilter{CamIDs: []int{5}}, []HumanLabel{}},
+	}
+	for _, tt := range tests {
+		got, err := HumanLabelsFromDB(conn, tt.filter)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %+v, %v\nwant %+v", tt.name, got, err, tt.want)
+		}
+	}
+}
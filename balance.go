@@ -0,0 +1,160 @@
+package frostclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BalanceOptions control BuildBalanced. A class without a target gets the
+// size of the smallest class, so the majority classes are undersampled.
+type BalanceOptions struct {
+	Targets       map[int]int
+	MaxPerStation int // per class, 0 => no limit
+	MaxPerDay     int // per class and station per UTC day, 0 => no limit
+	Seed          int64
+	Exclude       *SeriesReport // drops the flagged periods first when set
+}
+
+// CompositionReport describes a dataset before and after balancing.
+type CompositionReport struct {
+	Before   map[int]int
+	After    map[int]int
+	Stations map[int]int // distinct stations per class after
+	Days     map[int]int // distinct days per class after
+}
+
+func (r CompositionReport) String() string {
+	classes := make([]int, 0, len(r.Before))
+	for c := range r.Before {
+		classes = append(classes, c)
+	}
+	sort.Ints(classes)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%6s %8s %8s %8s %8s\n", "class", "before", "after", "stations", "days")
+	for _, c := range classes {
+		fmt.Fprintf(&b, "%6d %8d %8d %8d %8d\n", c, r.Before[c], r.After[c], r.Stations[c], r.Days[c])
+	}
+	return b.String()
+}
+
+// BuildBalanced picks observations from class2Obses so each class reaches its
+// target, spreading the picks over as many days as possible. The result only
+// depends on the input and opts.Seed. The observations of each class are
+// returned sorted by time.
+func BuildBalanced(class2Obses map[int][]ObsRoadweather, opts BalanceOptions) (map[int][]ObsRoadweather, CompositionReport) {
+	report := CompositionReport{
+		Before:   make(map[int]int),
+		After:    make(map[int]int),
+		Stations: make(map[int]int),
+		Days:     make(map[int]int),
+	}
+
+	if opts.Exclude != nil {
+		included := make(map[int][]ObsRoadweather, len(class2Obses))
+		for c, obses := range class2Obses {
+			included[c] = opts.Exclude.Exclude(obses)
+		}
+		class2Obses = included
+	}
+
+	minority := -1
+	for c, obses := range class2Obses {
+		report.Before[c] = len(obses)
+		if len(obses) > 0 && (minority < 0 || len(obses) < minority) {
+			minority = len(obses)
+		}
+	}
+
+	balanced := make(map[int][]ObsRoadweather)
+	for c, obses := range class2Obses {
+		target, ok := opts.Targets[c]
+		if !ok {
+			target = minority
+		}
+		picked := pickSpread(obses, target, opts)
+		sort.SliceStable(picked, func(i, j int) bool {
+			return picked[i].RefTime.Before(picked[j].RefTime)
+		})
+		balanced[c] = picked
+
+		stations := make(map[string]bool)
+		days := make(map[time.Time]bool)
+		for _, obs := range picked {
+			stations[obs.Station] = true
+			days[obs.RefTime.UTC().Truncate(24*time.Hour)] = true
+		}
+		report.After[c] = len(picked)
+		report.Stations[c] = len(stations)
+		report.Days[c] = len(days)
+	}
+	return balanced, report
+}
+
+// pickSpread picks up to target observations, taking one from each day in
+// turn, days and observations in seeded random order.
+func pickSpread(obses []ObsRoadweather, target int, opts BalanceOptions) []ObsRoadweather {
+	type day struct {
+		day   time.Time
+		rank  uint64
+		obses []int
+	}
+	rank := make([]uint64, len(obses))
+	days := make(map[time.Time]*day)
+	for i, obs := range obses {
+		rank[i] = seededHash(opts.Seed, fmt.Sprintf("%s/%d", obs.Station, obs.CamID), obs.RefTime)
+		d := obs.RefTime.UTC().Truncate(24 * time.Hour)
+		if _, ok := days[d]; !ok {
+			days[d] = &day{day: d, rank: seededHash(opts.Seed, "", d)}
+		}
+		days[d].obses = append(days[d].obses, i)
+	}
+
+	order := make([]*day, 0, len(days))
+	for _, d := range days {
+		sort.Slice(d.obses, func(i, j int) bool {
+			return rank[d.obses[i]] < rank[d.obses[j]]
+		})
+		order = append(order, d)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].rank != order[j].rank {
+			return order[i].rank < order[j].rank
+		}
+		return order[i].day.Before(order[j].day)
+	})
+
+	type stationDay struct {
+		station string
+		day     time.Time
+	}
+	perStation := make(map[string]int)
+	perDay := make(map[stationDay]int)
+	next := make([]int, len(order))
+	picked := []ObsRoadweather{}
+
+	for progress := true; progress && len(picked) < target; {
+		progress = false
+		for d := 0; d < len(order) && len(picked) < target; d++ {
+			for next[d] < len(order[d].obses) {
+				obs := obses[order[d].obses[next[d]]]
+				next[d]++
+				sd := stationDay{obs.Station, order[d].day}
+				if opts.MaxPerStation > 0 && perStation[obs.Station] >= opts.MaxPerStation {
+					continue
+				}
+				if opts.MaxPerDay > 0 && perDay[sd] >= opts.MaxPerDay {
+					continue
+				}
+				perStation[obs.Station]++
+				perDay[sd]++
+				picked = append(picked, obs)
+				progress = true
+				break
+			}
+		}
+	}
+	return picked
+}
@@ -0,0 +1,96 @@
+package frostclient
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestBuildBalanced(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	class2Obses := map[int][]ObsRoadweather{
+		DryE:  series(start, time.Hour, 240, DryE, "SN1:0", "SN2:0"), // 10 days
+		IceE:  series(start, 6*time.Hour, 20, IceE, "SN1:0"),         // 5 days
+		SnowE: series(start, 12*time.Hour, 6, SnowE, "SN3:0"),        // 3 days
+	}
+	tests := []struct {
+		name string
+		opts BalanceOptions
+		want map[int]int
+	}{
+		{"minority", BalanceOptions{Seed: 1}, map[int]int{DryE: 6, IceE: 6, SnowE: 6}},
+		{"targets", BalanceOptions{Targets: map[int]int{DryE: 50, IceE: 100}, Seed: 1}, map[int]int{DryE: 50, IceE: 20, SnowE: 6}},
+		{"per station", BalanceOptions{Targets: map[int]int{DryE: 50}, MaxPerStation: 10, Seed: 1}, map[int]int{DryE: 20, IceE: 6, SnowE: 6}},
+		{"per day", BalanceOptions{Targets: map[int]int{DryE: 100, IceE: 100}, MaxPerDay: 2, Seed: 1}, map[int]int{DryE: 40, IceE: 10, SnowE: 6}},
+	}
+	for _, tt := range tests {
+		balanced, report := BuildBalanced(class2Obses, tt.opts)
+		got := make(map[int]int)
+		for c, obses := range balanced {
+			got[c] = len(obses)
+			if !sort.SliceIsSorted(obses, func(i, j int) bool { return obses[i].RefTime.Before(obses[j].RefTime) }) {
+				t.Errorf("%s: class %d not sorted by time", tt.name, c)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(report.After, tt.want) {
+			t.Errorf("%s: kept %v, report %v, want %v", tt.name, got, report.After, tt.want)
+		}
+		if report.Before[DryE] != 480 || report.Before[IceE] != 20 || report.Before[SnowE] != 6 {
+			t.Errorf("%s: before %v", tt.name, report.Before)
+		}
+	}
+
+	a, _ := BuildBalanced(class2Obses, BalanceOptions{Seed: 1})
+	b, _ := BuildBalanced(class2Obses, BalanceOptions{Seed: 1})
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("BuildBalanced is not reproducible")
+	}
+}
+
+func TestPickSpread(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	obses := series(start, time.Hour, 240, DryE, "SN1:0") // 24 a day for 10 days
+	tests := []struct {
+		name   string
+		target int
+		opts   BalanceOptions
+		want   int
+		days   int
+	}{
+		{"one per day", 10, BalanceOptions{Seed: 1}, 10, 10},
+		{"fewer than days", 4, BalanceOptions{Seed: 1}, 4, 4},
+		{"two rounds", 15, BalanceOptions{Seed: 1}, 15, 10},
+		{"more than available", 1000, BalanceOptions{Seed: 1}, 240, 10},
+		{"per day limit", 1000, BalanceOptions{MaxPerDay: 3, Seed: 1}, 30, 10},
+		{"per station limit", 1000, BalanceOptions{MaxPerStation: 7, Seed: 1}, 7, 7},
+		{"none", 0, BalanceOptions{Seed: 1}, 0, 0},
+	}
+	for _, tt := range tests {
+		picked := pickSpread(obses, tt.target, tt.opts)
+		days := make(map[time.Time]int)
+		for _, obs := range picked {
+			days[obs.RefTime.Truncate(24*time.Hour)]++
+		}
+		if len(picked) != tt.want || len(days) != tt.days {
+			t.Errorf("%s: picked %d on %d days, want %d on %d", tt.name, len(picked), len(days), tt.want, tt.days)
+		}
+		// Taking one a day in turn, no day is ahead of another by more than one
+		lo, hi := len(picked), 0
+		for _, n := range days {
+			if n < lo {
+				lo = n
+			}
+			if n > hi {
+				hi = n
+			}
+		}
+		if tt.opts.MaxPerDay == 0 && tt.target < len(obses) && len(days) == 10 && hi-lo > 1 {
+			t.Errorf("%s: picks per day %v", tt.name, days)
+		}
+	}
+
+	if a, b := pickSpread(obses, 10, BalanceOptions{Seed: 1}), pickSpread(obses, 10, BalanceOptions{Seed: 2}); reflect.DeepEqual(a, b) {
+		t.Errorf("seeds 1 and 2 picked the same observations")
+	}
+}
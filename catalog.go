@@ -0,0 +1,253 @@
+package frostclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// Catalog restricts the observation requests to the sources valid within each
+// requested time window when set. GetStationCameras loads it from CatalogPath
+// when nil and keeps it updated.
+var Catalog *StationCatalog
+
+// CatalogPath is where GetStationCameras loads and saves the station catalog.
+// Empty, the default, leaves the catalog off unless Catalog is set, so nothing
+// is written unless asked for.
+var CatalogPath = ""
+
+// StationsSince is the start of the station metadata history. Stations valid
+// at some point since then are matched and kept in the catalog, so the ones
+// decommissioned during a dataset are still requested for their valid time.
+var StationsSince = time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+
+// StationRecord is one version of the metadata of a station. A zero ValidTo
+// means the station is still in operation.
+type StationRecord struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	ValidFrom      time.Time `json:"validFrom"`
+	ValidTo        time.Time `json:"validTo,omitempty"`
+	StationHolders []string  `json:"stationHolders"`
+	ExternalIds    []string  `json:"externalIds"`
+	Municipality   string    `json:"municipality"`
+	MunicipalityID int       `json:"municipalityId"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Masl           int       `json:"masl"`
+	FirstSeen      time.Time `json:"firstSeen"`
+	LastSeen       time.Time `json:"lastSeen"`
+}
+
+// ValidIn reports whether r overlaps the window [from, to).
+func (r StationRecord) ValidIn(from, to time.Time) bool {
+	return r.ValidFrom.Before(to) && (r.ValidTo.IsZero() || r.ValidTo.After(from))
+}
+
+func (r StationRecord) sameMetadata(o StationRecord) bool {
+	return r.ID == o.ID && r.Name == o.Name &&
+		r.ValidFrom.Equal(o.ValidFrom) && r.ValidTo.Equal(o.ValidTo) &&
+		slices.Equal(r.StationHolders, o.StationHolders) && slices.Equal(r.ExternalIds, o.ExternalIds) &&
+		r.Municipality == o.Municipality && r.MunicipalityID == o.MunicipalityID &&
+		r.Latitude == o.Latitude && r.Longitude == o.Longitude && r.Masl == o.Masl
+}
+
+// StationCatalog is the metadata history of the stations, stored as JSON in
+// Path. Stations maps station ID to its records, oldest first.
+type StationCatalog struct {
+	Path     string                     `json:"-"`
+	Updated  time.Time                  `json:"updated"`
+	Stations map[string][]StationRecord `json:"stations"`
+}
+
+// LoadStationCatalog reads the catalog in path. A missing file gives an empty
+// catalog.
+func LoadStationCatalog(path string) (*StationCatalog, error) {
+	c := &StationCatalog{Path: path, Stations: make(map[string][]StationRecord)}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os.Open(%s): %v", path, err)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(c)
+	if err != nil {
+		return nil, fmt.Errorf("LoadStationCatalog(%s): %v", path, err)
+	}
+	if c.Stations == nil {
+		c.Stations = make(map[string][]StationRecord)
+	}
+	return c, nil
+}
+
+// Save writes the catalog to Path.
+func (c *StationCatalog) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll(%s): %v", filepath.Dir(c.Path), err)
+	}
+	tmp := c.Path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("os.Create(%s): %v", tmp, err)
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(c)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("StationCatalog.Save(): %v", err)
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.Path)
+}
+
+// Update adds the metadata of stations to the catalog and returns the number
+// of new records. A station whose metadata changed gets a new record. One with
+// a new ValidFrom closes the previous record at it unless Frost already did,
+// one with the same ValidFrom supersedes the previous record of the period,
+// see ValidIn.
+func (c *StationCatalog) Update(stations []Source) int {
+	now := time.Now().UTC()
+	added := 0
+	for s := 0; s < len(stations); s++ {
+		st := stations[s]
+		rec := recordOf(st, now)
+
+		history := c.Stations[st.ID]
+		if i := latestOf(history, rec.ValidFrom); i >= 0 && history[i].sameMetadata(rec) {
+			history[i].LastSeen = now
+			continue
+		}
+		if len(history) > 0 {
+			last := &history[len(history)-1]
+			if last.ValidTo.IsZero() && rec.ValidFrom.After(last.ValidFrom) {
+				last.ValidTo = rec.ValidFrom
+			}
+		}
+		// A record of the same period with new metadata, e.g. moved or
+		// validTo set when decommissioned, is kept after the old one
+		c.Stations[st.ID] = append(history, rec)
+		added++
+	}
+
+	for id := range c.Stations {
+		sort.SliceStable(c.Stations[id], func(i, j int) bool {
+			return c.Stations[id][i].ValidFrom.Before(c.Stations[id][j].ValidFrom)
+		})
+	}
+	c.Updated = now
+	return added
+}
+
+// recordOf returns the metadata of st as a record first and last seen at now.
+func recordOf(st Source, now time.Time) StationRecord {
+	rec := StationRecord{
+		ID:             st.ID,
+		Name:           st.Name,
+		ValidFrom:      st.ValidFrom,
+		ValidTo:        st.ValidTo,
+		StationHolders: st.StationHolders,
+		ExternalIds:    st.ExternalIds,
+		Municipality:   st.Municipality,
+		MunicipalityID: st.MunicipalityID,
+		Masl:           st.Masl,
+		FirstSeen:      now,
+		LastSeen:       now,
+	}
+	if len(st.Geometry.Coordinates) >= 2 {
+		rec.Longitude = st.Geometry.Coordinates[0]
+		rec.Latitude = st.Geometry.Coordinates[1]
+	}
+	return rec
+}
+
+// latestOf returns the index of the last record in history with validFrom,
+// -1 if there is none.
+func latestOf(history []StationRecord, validFrom time.Time) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].ValidFrom.Equal(validFrom) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Refresh updates the catalog with the SVV stations valid at some point since
+// from and saves it.
+func (c *StationCatalog) Refresh(from time.Time) error {
+	res, err := stationHolderReq(svvStationsURL(from))
+	if err != nil {
+		return fmt.Errorf("stationHolderReq(): %v", err)
+	}
+	c.Update(res.Data)
+	return c.Save()
+}
+
+// ValidIn returns the record of station id valid within [from, to). A source
+// ID like "SN12345:0" is looked up as "SN12345". Of the records of a period
+// only the latest counts, the earlier ones are kept as history.
+func (c *StationCatalog) ValidIn(id string, from, to time.Time) (StationRecord, bool) {
+	id = strings.Split(id, ":")[0]
+	history := c.Stations[id]
+	for i := len(history) - 1; i >= 0; i-- {
+		if latestOf(history, history[i].ValidFrom) != i {
+			continue
+		}
+		if history[i].ValidIn(from, to) {
+			return history[i], true
+		}
+	}
+	return StationRecord{}, false
+}
+
+// FilterSources returns the sources valid within [from, to). Sources not in
+// the catalog are kept since nothing is known about them.
+func (c *StationCatalog) FilterSources(sources []string, from, to time.Time) []string {
+	valid := []string{}
+	for _, id := range sources {
+		_, known := c.Stations[strings.Split(id, ":")[0]]
+		if !known {
+			valid = append(valid, id)
+			continue
+		}
+		if _, ok := c.ValidIn(id, from, to); ok {
+			valid = append(valid, id)
+		}
+	}
+	return valid
+}
+
+// loadCatalog loads Catalog from CatalogPath unless it is loaded or off.
+func loadCatalog() {
+	if Catalog != nil || CatalogPath == "" {
+		return
+	}
+	c, err := LoadStationCatalog(CatalogPath)
+	if err != nil {
+		log.Printf("LoadStationCatalog: %v. Not filtering sources by validity", err)
+		return
+	}
+	Catalog = c
+}
+
+// validSources returns the sources to request for the window [from, to),
+// filtered by Catalog when set.
+func validSources(sources []string, from, to time.Time) []string {
+	if Catalog != nil {
+		sources = Catalog.FilterSources(sources, from, to)
+	}
+	return sources
+}
@@ -0,0 +1,111 @@
+package frostclient
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func day(d int) time.Time {
+	return time.Date(2023, 2, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestStationRecordValidIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		rec      StationRecord
+		from, to time.Time
+		want     bool
+	}{
+		{"open, window after start", StationRecord{ValidFrom: day(1)}, day(5), day(6), true},
+		{"open, window before start", StationRecord{ValidFrom: day(5)}, day(1), day(5), false},
+		{"open, window across start", StationRecord{ValidFrom: day(5)}, day(4), day(6), true},
+		{"closed, window inside", StationRecord{ValidFrom: day(1), ValidTo: day(10)}, day(5), day(6), true},
+		{"closed, window after end", StationRecord{ValidFrom: day(1), ValidTo: day(10)}, day(10), day(11), false},
+		{"closed, window across end", StationRecord{ValidFrom: day(1), ValidTo: day(10)}, day(9), day(11), true},
+	}
+	for _, tt := range tests {
+		if got := tt.rec.ValidIn(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: ValidIn = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStationCatalogUpdate(t *testing.T) {
+	at := func(lat float64, from, to time.Time) Source {
+		return Source{ID: "SN1", Name: "Road", Geometry: Geometry{Coordinates: []float64{10, lat}}, ValidFrom: from, ValidTo: to}
+	}
+	c := &StationCatalog{Stations: make(map[string][]StationRecord)}
+
+	steps := []struct {
+		name   string
+		source Source
+		added  int
+		// latitudes and ValidTo of the records after the step
+		lats    []float64
+		validTo []time.Time
+	}{
+		{"new station", at(60, day(1), time.Time{}), 1, []float64{60}, []time.Time{{}}},
+		{"unchanged", at(60, day(1), time.Time{}), 0, []float64{60}, []time.Time{{}}},
+		{"moved in the same period", at(61, day(1), time.Time{}), 1, []float64{60, 61}, []time.Time{{}, {}}},
+		{"new period", at(62, day(10), time.Time{}), 1, []float64{60, 61, 62}, []time.Time{{}, day(10), {}}},
+		{"decommissioned", at(62, day(10), day(20)), 1, []float64{60, 61, 62, 62}, []time.Time{{}, day(10), {}, day(20)}},
+	}
+	for _, s := range steps {
+		if added := c.Update([]Source{s.source}); added != s.added {
+			t.Errorf("%s: added %d records, want %d", s.name, added, s.added)
+		}
+		lats, validTo := []float64{}, []time.Time{}
+		for _, rec := range c.Stations["SN1"] {
+			lats = append(lats, rec.Latitude)
+			validTo = append(validTo, rec.ValidTo)
+		}
+		if !reflect.DeepEqual(lats, s.lats) || !reflect.DeepEqual(validTo, s.validTo) {
+			t.Errorf("%s: records at %v valid to %v, want %v valid to %v", s.name, lats, validTo, s.lats, s.validTo)
+		}
+	}
+
+	lookups := []struct {
+		id       string
+		from, to time.Time
+		lat      float64
+		ok       bool
+	}{
+		{"SN1", day(2), day(3), 61, true}, // the superseding record of the first period
+		{"SN1:0", day(12), day(13), 62, true},
+		{"SN1", day(20), day(21), 0, false},
+		{"SN2", day(2), day(3), 0, false},
+	}
+	for _, l := range lookups {
+		rec, ok := c.ValidIn(l.id, l.from, l.to)
+		if ok != l.ok || rec.Latitude != l.lat {
+			t.Errorf("ValidIn(%s, %s) = %v at %g, want %v at %g", l.id, l.from.Format("2006-01-02"), ok, rec.Latitude, l.ok, l.lat)
+		}
+	}
+
+	got := c.FilterSources([]string{"SN1:0", "SN2:0"}, day(21), day(22))
+	if !reflect.DeepEqual(got, []string{"SN2:0"}) {
+		t.Errorf("FilterSources after decommissioning = %v, want the unknown SN2:0 only", got)
+	}
+}
+
+func TestStationCatalogSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog", "stations.json")
+	c, err := LoadStationCatalog(path)
+	if err != nil {
+		t.Fatalf("LoadStationCatalog of a missing file: %v", err)
+	}
+	c.Update([]Source{{ID: "SN1", ValidFrom: day(1), ExternalIds: []string{"100101"}}})
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadStationCatalog(path)
+	if err != nil {
+		t.Fatalf("LoadStationCatalog: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Stations["SN1"][0].ExternalIds, []string{"100101"}) || !loaded.Updated.Equal(c.Updated) {
+		t.Errorf("loaded %+v, want %+v", loaded, c)
+	}
+}
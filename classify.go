@@ -0,0 +1,169 @@
+package frostclient
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ElementMask records which of ObsWater, ObsSnow and ObsIce an ObsRoadweather
+// has a value for.
+type ElementMask uint8
+
+const (
+	WaterFilmPresent ElementMask = 1 << ObsWater
+	SnowPresent      ElementMask = 1 << ObsSnow
+	IcePresent       ElementMask = 1 << ObsIce
+	AllPresent                   = WaterFilmPresent | SnowPresent | IcePresent
+)
+
+func (m ElementMask) String() string {
+	s := ""
+	for _, e := range []struct {
+		bit  ElementMask
+		name string
+	}{{IcePresent, "Ice"}, {WaterFilmPresent, "Water"}, {SnowPresent, "Snow"}} {
+		if m&e.bit == 0 {
+			continue
+		}
+		if s != "" {
+			s += "+"
+		}
+		s += e.name
+	}
+	if s == "" {
+		return "None"
+	}
+	return s
+}
+
+// Complete reports whether obs has ice, water film and snow values.
+func (obs ObsRoadweather) Complete() bool {
+	return obs.Present&AllPresent == AllPresent
+}
+
+var ErrIncomplete = errors.New("incomplete observation")
+
+// RefuseIncomplete makes the GetDataFromFrost functions skip observations
+// missing ice, water film or snow. When false they are classified with the
+// missing values as 0 and can be found by their Present mask.
+var RefuseIncomplete = true
+
+// Scheme is a set of road condition classes and the rule assigning them.
+type Scheme struct {
+	Name     string
+	Classes  map[int]string
+	classify func(ice, water, snow float32) int
+}
+
+// Classify returns the class of obs, ErrIncomplete if a value is missing.
+func (s Scheme) Classify(obs ObsRoadweather) (int, error) {
+	if !obs.Complete() {
+		return -1, fmt.Errorf("%s: %w, has %s", s.Name, ErrIncomplete, obs.Present)
+	}
+	return s.ClassifyPartial(obs), nil
+}
+
+// ClassifyPartial returns the class of obs taking missing values as 0.
+func (s Scheme) ClassifyPartial(obs ObsRoadweather) int {
+	return s.classify(obs.IceThickness, obs.WaterFilmThickness, obs.SnowThickness)
+}
+
+var ClassesMap4 = map[int]string{
+	0: "Dry",
+	1: "Wet",
+	2: "SnowAndOrIceNoWater",
+	3: "SnowAndOrIceWithWater",
+}
+
+// Dry  int = 0, Wet int = 1 // No snow an Ice, SnowAndOrIce int = 2
+var Scheme3 = Scheme{
+	Name:    "3classes",
+	Classes: ClassesMap3,
+	classify: func(ice, water, snow float32) int {
+		if ice == 0.0 && water == 0.0 && snow == 0.0 {
+			return Dry
+		} else if ice > 0.0 || snow > 0.0 {
+			return SnowAndOrIce // Can also be be water or no-water
+		}
+		return Wet // Can not be Snow an or Ice becasue ^
+	},
+}
+
+var Scheme4 = Scheme{
+	Name:    "4classes",
+	Classes: ClassesMap4,
+	classify: func(ice, water, snow float32) int {
+		if ice == 0.0 && water == 0.0 && snow == 0.0 {
+			return Dry4
+		} else if ice == 0.0 && snow == 0.0 {
+			return Wet4
+		} else if water == 0.0 {
+			return SnowAndOrIceNoWater4
+		}
+		return SnowAndOrIceWithWather4
+	},
+}
+
+var Scheme6 = Scheme{
+	Name:    "6classes",
+	Classes: ClassesMap6,
+	classify: func(ice, water, snow float32) int {
+		if ice == 0.0 && water == 0.0 && snow == 0.0 {
+			return Dry6
+		} else if ice > 0.0 && snow > 0.0 && water == 0.0 {
+			return SnowAndIce6
+		} else if water > 0.0 && (ice > 0.0 || snow > 0.0) {
+			return SnowAndIceAndWetOrWetAndIceOrWetAndSnow6
+		} else if ice > 0.0 { // => Ice only
+			return Ice6
+		} else if snow > 0.0 { // => Snow only
+			return Snow6
+		}
+		return Wet6
+	},
+}
+
+var Scheme8 = Scheme{
+	Name:    "8classes",
+	Classes: ClassesMap8,
+	classify: func(ice, water, snow float32) int {
+		if ice == 0.0 && water == 0.0 && snow == 0.0 {
+			return DryE
+		} else if ice > 0.0 && water > 0.0 && snow > 0.0 {
+			return SnowAndIceAndWetE
+		} else if ice > 0.0 && snow > 0.0 {
+			return SnowAndIceE
+		} else if ice > 0.0 && water > 0.0 {
+			return WetAndIceE
+		} else if snow > 0.0 && water > 0.0 {
+			return WetAndSnowE
+		} else if ice > 0.0 { // => Ice only
+			return IceE
+		} else if snow > 0.0 { // => Snow only
+			return SnowE
+		}
+		return WetE
+	},
+}
+
+// Schemes lists the schemes by name.
+var Schemes = map[string]Scheme{
+	Scheme3.Name: Scheme3,
+	Scheme4.Name: Scheme4,
+	Scheme6.Name: Scheme6,
+	Scheme8.Name: Scheme8,
+}
+
+// classify classifies obs by scheme, refusing incomplete observations when
+// refuse is set, and counts them in incomplete.
+func classify(scheme Scheme, obs ObsRoadweather, refuse bool, incomplete map[string]int) (int, bool) {
+	class, err := scheme.Classify(obs)
+	if err == nil {
+		return class, true
+	}
+	incomplete[obs.Present.String()]++
+	if refuse {
+		return -1, false
+	}
+	return scheme.ClassifyPartial(obs), true
+}
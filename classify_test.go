@@ -0,0 +1,160 @@
+package frostclient
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// The classifications of the GetDataFromFrost functions before the schemes,
+// for non-negative thicknesses.
+var oldClassifiers = map[string]func(ice, water, snow float32) int{
+	"3classes": func(ice, water, snow float32) int {
+		if ice == 0.0 && water == 0.0 && snow == 0.0 {
+			return Dry
+		} else if ice > 0.0 || snow > 0.0 {
+			return SnowAndOrIce
+		}
+		return Wet
+	},
+	"4classes": func(ice, water, snow float32) int {
+		if ice == 0.0 && water == 0.0 && snow == 0.0 {
+			return Dry4
+		} else if ice == 0.0 && snow == 0.0 && water > 0.0 {
+			return Wet4
+		} else if (ice > 0.0 || snow > 0.0) && water == 0.0 {
+			return SnowAndOrIceNoWater4
+		}
+		return SnowAndOrIceWithWather4
+	},
+	"6classes": func(ice, water, snow float32) int {
+		if ice == 0.0 && water == 0.0 && snow == 0.0 {
+			return Dry6
+		} else if ice > 0.0 && water > 0.0 && snow > 0.0 {
+			return SnowAndIceAndWetOrWetAndIceOrWetAndSnow6
+		} else if ice > 0.0 && snow > 0.0 && water == 0.0 {
+			return SnowAndIce6
+		} else if ice > 0.0 && water > 0.0 && snow == 0.0 {
+			return SnowAndIceAndWetOrWetAndIceOrWetAndSnow6
+		} else if snow > 0.0 && water > 0.0 && ice == 0.0 {
+			return SnowAndIceAndWetOrWetAndIceOrWetAndSnow6
+		} else if ice > 0.0 {
+			return Ice6
+		} else if snow > 0.0 {
+			return Snow6
+		}
+		return Wet6
+	},
+	"8classes": func(ice, water, snow float32) int {
+		if ice == 0.0 && water == 0.0 && snow == 0.0 {
+			return DryE
+		} else if ice > 0.0 && water > 0.0 && snow > 0.0 {
+			return SnowAndIceAndWetE
+		} else if ice > 0.0 && snow > 0.0 && water == 0.0 {
+			return SnowAndIceE
+		} else if ice > 0.0 && water > 0.0 && snow == 0.0 {
+			return WetAndIceE
+		} else if snow > 0.0 && water > 0.0 && ice == 0.0 {
+			return WetAndSnowE
+		} else if ice > 0.0 {
+			return IceE
+		} else if snow > 0.0 {
+			return SnowE
+		}
+		return WetE
+	},
+}
+
+func TestSchemeParity(t *testing.T) {
+	values := []float32{0, 0.01, 0.5, 3}
+	for name, old := range oldClassifiers {
+		scheme, ok := Schemes[name]
+		if !ok {
+			t.Errorf("no scheme %s", name)
+			continue
+		}
+		for _, ice := range values {
+			for _, water := range values {
+				for _, snow := range values {
+					obs := ObsRoadweather{IceThickness: ice, WaterFilmThickness: water, SnowThickness: snow, Present: AllPresent}
+					got, err := scheme.Classify(obs)
+					if want := old(ice, water, snow); err != nil || got != want {
+						t.Errorf("%s ice %g water %g snow %g: class %d, %v, want %d", name, ice, water, snow, got, err, want)
+					}
+					if _, ok := scheme.Classes[got]; !ok {
+						t.Errorf("%s: class %d has no name", name, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestClassifyIncomplete(t *testing.T) {
+	obs := ObsRoadweather{RefTime: time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), IceThickness: 0.2, Present: IcePresent | SnowPresent}
+	if _, err := Scheme8.Classify(obs); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("Classify of %s = %v, want ErrIncomplete", obs.Present, err)
+	}
+	if got := Scheme8.ClassifyPartial(obs); got != IceE {
+		t.Errorf("ClassifyPartial = %d, want Ice", got)
+	}
+
+	tests := []struct {
+		refuse bool
+		obs    ObsRoadweather
+		class  int
+		ok     bool
+	}{
+		{true, obs, -1, false},
+		{false, obs, IceE, true},
+		{true, ObsRoadweather{WaterFilmThickness: 0.1, Present: AllPresent}, WetE, true},
+	}
+	for _, tt := range tests {
+		incomplete := make(map[string]int)
+		class, ok := classify(Scheme8, tt.obs, tt.refuse, incomplete)
+		if class != tt.class || ok != tt.ok {
+			t.Errorf("refuse %v, %s: classify = %d, %v, want %d, %v", tt.refuse, tt.obs.Present, class, ok, tt.class, tt.ok)
+		}
+		if want := !tt.obs.Complete(); (incomplete["Ice+Snow"] == 1) != want {
+			t.Errorf("refuse %v, %s: incomplete = %v", tt.refuse, tt.obs.Present, incomplete)
+		}
+	}
+}
+
+func TestElementMaskString(t *testing.T) {
+	tests := []struct {
+		m    ElementMask
+		want string
+	}{
+		{0, "None"},
+		{IcePresent, "Ice"},
+		{SnowPresent | WaterFilmPresent, "Water+Snow"},
+		{AllPresent, "Ice+Water+Snow"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("%08b: String = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestParseRoadweatherPresent(t *testing.T) {
+	units := &UnitValidator{Report: UnitReport{Counts: map[string]int{}, Unexpected: map[string]int{}, Rejected: map[string]int{}}}
+	quality := &QualityFilter{Policy: DefaultQualityPolicy, Report: QualityReport{Rejected: map[string]int{}}}
+	at := ObservationsAtRefTime{
+		SourceID:      "SN1:0",
+		ReferenceTime: time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC),
+		Observations: []Observation{
+			{ElementID: "road_ice_thickness", Value: 0.3, Unit: "mm", QualityCode: 0},
+			{ElementID: "road_water_film_thickness", Value: 0.1, Unit: "mm", QualityCode: 5}, // rejected
+			{ElementID: "road_snow_thickness", Value: 1, Unit: "furlong", QualityCode: 0},    // not convertible
+		},
+	}
+	obs := parseRoadweather(at, units, quality, DefaultSensorPriority)
+	if obs.Present != IcePresent || obs.IceThickness != 0.3 || obs.WaterFilmThickness != 0 || obs.SnowThickness != 0 {
+		t.Errorf("parsed %+v, want ice 0.3 only", obs)
+	}
+	if obs.Station != "SN1:0" || obs.FrostID != "SN1:0" || !obs.RefTime.Equal(at.ReferenceTime) {
+		t.Errorf("parsed %+v, want SN1:0 at %s", obs, at.ReferenceTime)
+	}
+}
@@ -0,0 +1,39 @@
+// Command verifysnapshot checks the outputs of a dataset build against its
+// snapshot manifest.
+//
+//	verifysnapshot snapshot.json
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	frostclient "github.com/metno/frostclient-roadweather"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s snapshot.json\n", os.Args[0])
+		os.Exit(2)
+	}
+	path := os.Args[1]
+
+	snapshot, err := frostclient.LoadSnapshot(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+	fmt.Printf("Snapshot of %s %s/%s, client %s, Frost API %s\n", snapshot.Scheme,
+		snapshot.Start.Format("2006-01-02"), snapshot.Stop.Format("2006-01-02"),
+		snapshot.ClientVersion, snapshot.FrostAPIVersion)
+
+	problems := snapshot.Verify(filepath.Dir(path))
+	for _, p := range problems {
+		fmt.Printf("FAIL %v\n", p)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+	fmt.Printf("OK %d files\n", len(snapshot.Outputs))
+}
@@ -0,0 +1,142 @@
+package frostclient
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CSVHeader is the first row written by CSVWriter. Thicknesses are in mm,
+// empty when missing, and times in RFC 3339 UTC.
+var CSVHeader = []string{"refTime", "station", "frostID", "camID", "ice", "water", "snow", "classId", "className", "scheme"}
+
+// CSVWriter writes classified observations as CSV rows under CSVHeader.
+type CSVWriter struct {
+	w           *csv.Writer
+	scheme      Scheme
+	wroteHeader bool
+}
+
+func NewCSVWriter(w io.Writer, scheme Scheme) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w), scheme: scheme}
+}
+
+// Write writes obs with the name of its class in the scheme.
+func (c *CSVWriter) Write(obs ObsRoadweather) error {
+	return c.WriteNamed(obs, c.scheme.Classes[obs.Class])
+}
+
+// WriteNamed writes obs with className, for classes named outside the scheme
+// like those of GetObsMapForLabelApp.
+func (c *CSVWriter) WriteNamed(obs ObsRoadweather, className string) error {
+	if !c.wroteHeader {
+		if err := c.w.Write(CSVHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	return c.w.Write([]string{
+		obs.RefTime.UTC().Format(time.RFC3339),
+		obs.Station,
+		obs.FrostID,
+		strconv.Itoa(obs.CamID),
+		formatMM(obs, IcePresent, obs.IceThickness),
+		formatMM(obs, WaterFilmPresent, obs.WaterFilmThickness),
+		formatMM(obs, SnowPresent, obs.SnowThickness),
+		strconv.Itoa(obs.Class),
+		className,
+		c.scheme.Name,
+	})
+}
+
+// Flush writes the buffered rows, and the header if there were none.
+func (c *CSVWriter) Flush() error {
+	if !c.wroteHeader {
+		if err := c.w.Write(CSVHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// formatMM is empty for missing values so they are not mistaken for 0 mm.
+func formatMM(obs ObsRoadweather, bit ElementMask, v float32) string {
+	if obs.Present&bit == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(v), 'g', -1, 32)
+}
+
+// WriteCSV writes class2Obses, as returned by the GetDataFromFrost
+// functions, ordered by class and time.
+func WriteCSV(w io.Writer, scheme Scheme, class2Obses map[int][]ObsRoadweather) error {
+	classes := make([]int, 0, len(class2Obses))
+	for c := range class2Obses {
+		classes = append(classes, c)
+	}
+	sort.Ints(classes)
+
+	cw := NewCSVWriter(w, scheme)
+	for _, c := range classes {
+		for _, obs := range sortedByTime(class2Obses[c]) {
+			if err := cw.Write(obs); err != nil {
+				return err
+			}
+		}
+	}
+	return cw.Flush()
+}
+
+// WriteNamedCSV writes name2Obses, as returned by GetObsMapForLabelApp, with
+// the map keys as class names, ordered by name and time.
+func WriteNamedCSV(w io.Writer, scheme Scheme, name2Obses map[string][]ObsRoadweather) error {
+	names := make([]string, 0, len(name2Obses))
+	for n := range name2Obses {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	cw := NewCSVWriter(w, scheme)
+	for _, n := range names {
+		for _, obs := range sortedByTime(name2Obses[n]) {
+			if err := cw.WriteNamed(obs, n); err != nil {
+				return err
+			}
+		}
+	}
+	return cw.Flush()
+}
+
+// WriteCSVFile writes class2Obses to the file path.
+func WriteCSVFile(path string, scheme Scheme, class2Obses map[int][]ObsRoadweather) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("os.Create(%s): %v", path, err)
+	}
+	if err := WriteCSV(f, scheme, class2Obses); err != nil {
+		f.Close()
+		return fmt.Errorf("WriteCSV(%s): %v", path, err)
+	}
+	return f.Close()
+}
+
+func sortedByTime(obses []ObsRoadweather) []ObsRoadweather {
+	sorted := make([]ObsRoadweather, len(obses))
+	copy(sorted, obses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].RefTime.Equal(sorted[j].RefTime) {
+			return sorted[i].RefTime.Before(sorted[j].RefTime)
+		}
+		if sorted[i].Station != sorted[j].Station {
+			return sorted[i].Station < sorted[j].Station
+		}
+		return sorted[i].CamID < sorted[j].CamID
+	})
+	return sorted
+}
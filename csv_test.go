@@ -0,0 +1,81 @@
+package frostclient
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	class2Obses := map[int][]ObsRoadweather{
+		IceE: {
+			{RefTime: at.Add(time.Hour), Station: "SN2:0", FrostID: "SN2:0", CamID: 7, IceThickness: 0.25, Class: IceE, Present: IcePresent | SnowPresent},
+			{RefTime: at, Station: "SN1:0", FrostID: "SN1:0", CamID: 3, IceThickness: 1, Class: IceE, Present: AllPresent},
+		},
+		DryE: {
+			{RefTime: at, Station: "SN1:0", FrostID: "SN1:0", CamID: 3, Class: DryE, Present: AllPresent},
+		},
+	}
+	var b bytes.Buffer
+	if err := WriteCSV(&b, Scheme8, class2Obses); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	want := strings.Join([]string{
+		strings.Join(CSVHeader, ","),
+		"2023-02-10T12:00:00Z,SN1:0,SN1:0,3,0,0,0,0,Dry,8classes",
+		"2023-02-10T12:00:00Z,SN1:0,SN1:0,3,1,0,0,3,Ice,8classes",
+		"2023-02-10T13:00:00Z,SN2:0,SN2:0,7,0.25,,0,3,Ice,8classes", // no water film value
+		"",
+	}, "\n")
+	if got := b.String(); got != want {
+		t.Errorf("WriteCSV:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteNamedCSV(t *testing.T) {
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	name2Obses := map[string][]ObsRoadweather{
+		"Water": {{RefTime: at, Station: "SN1:0", WaterFilmThickness: 0.5, Class: WetE, Present: AllPresent}},
+		"Dry":   {{RefTime: at, Station: "SN2:0", Class: DryE, Present: AllPresent}},
+	}
+	var b bytes.Buffer
+	if err := WriteNamedCSV(&b, Scheme8, name2Obses); err != nil {
+		t.Fatalf("WriteNamedCSV: %v", err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("reading back: %v", err)
+	}
+	names := []string{}
+	for _, r := range records[1:] {
+		names = append(names, r[8])
+	}
+	if !reflect.DeepEqual(records[0], CSVHeader) || !reflect.DeepEqual(names, []string{"Dry", "Water"}) {
+		t.Errorf("records %v", records)
+	}
+}
+
+func TestCSVEmpty(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteCSV(&b, Scheme8, nil); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	if got, want := b.String(), strings.Join(CSVHeader, ",")+"\n"; got != want {
+		t.Errorf("empty dataset = %q, want the header only", got)
+	}
+}
+
+func TestWriteCSVFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.csv")
+	if err := WriteCSVFile(path, Scheme8, map[int][]ObsRoadweather{DryE: {{Station: "SN1:0"}}}); err != nil {
+		t.Errorf("WriteCSVFile: %v", err)
+	}
+	if err := WriteCSVFile(filepath.Join(path, "not-a-dir.csv"), Scheme8, nil); err == nil {
+		t.Errorf("WriteCSVFile under a file succeeded")
+	}
+}
@@ -0,0 +1,237 @@
+package frostclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+)
+
+type FlagKind string
+
+const (
+	FlagGap        FlagKind = "gap"
+	FlagConstant   FlagKind = "constant"
+	FlagJump       FlagKind = "jump"
+	FlagOutOfRange FlagKind = "out-of-range"
+)
+
+// Flag is a suspicious period in the series of an element at a station.
+type Flag struct {
+	Station string
+	Element string
+	Kind    FlagKind
+	Start   time.Time
+	End     time.Time
+	Value   float32 // mm, the constant value, the jump, or the value furthest out of range
+}
+
+func (f Flag) String() string {
+	return fmt.Sprintf("%s %s %s %s/%s %g mm", f.Station, f.Element, f.Kind,
+		f.Start.Format("2006-01-02T15:04Z"), f.End.Format("2006-01-02T15:04Z"), f.Value)
+}
+
+// SeriesChecks are the limits for AnalyzeSeries, a zero limit turns its
+// check off. Values are in mm.
+type SeriesChecks struct {
+	MaxGap      time.Duration // longest time without a value
+	MaxConstant time.Duration // longest time a non-zero value may stay the same
+	MaxJump     float32       // largest change between two values
+	Min, Max    float32       // range of valid values, off when both are 0
+}
+
+// DefaultSeriesChecks flag what the road sensors should never report.
+var DefaultSeriesChecks = SeriesChecks{
+	MaxGap:      6 * time.Hour,
+	MaxConstant: 3 * 24 * time.Hour,
+	MaxJump:     20,
+	Min:         0,
+	Max:         100,
+}
+
+// SeriesReport holds the flags found by AnalyzeSeries. It is a Sampler
+// dropping the observations in flagged periods, see Exclude.
+type SeriesReport struct {
+	Flags []Flag
+}
+
+// String summarises the flags by station and kind.
+func (r SeriesReport) String() string {
+	type key struct {
+		station string
+		kind    FlagKind
+	}
+	counts := make(map[key]int)
+	flagged := make(map[string]bool)
+	for _, f := range r.Flags {
+		counts[key{f.Station, f.Kind}]++
+		flagged[f.Station] = true
+	}
+	stations := maps.Keys(flagged)
+	sort.Strings(stations)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Data quality: %d flags at %d stations\n", len(r.Flags), len(stations))
+	for _, st := range stations {
+		kinds := []string{}
+		for _, kind := range []FlagKind{FlagGap, FlagConstant, FlagJump, FlagOutOfRange} {
+			if n := counts[key{st, kind}]; n > 0 {
+				kinds = append(kinds, fmt.Sprintf("%s %d", kind, n))
+			}
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", st, strings.Join(kinds, ", "))
+	}
+	return b.String()
+}
+
+// Flagged reports whether station has a flagged period, other than a gap,
+// covering t.
+func (r SeriesReport) Flagged(station string, t time.Time) bool {
+	for _, f := range r.Flags {
+		if f.Kind != FlagGap && f.Station == station && !t.Before(f.Start) && !t.After(f.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// Exclude returns the observations not in a flagged period.
+func (r SeriesReport) Exclude(obses []ObsRoadweather) []ObsRoadweather {
+	byStation := make(map[string][]Flag)
+	for _, f := range r.Flags {
+		if f.Kind != FlagGap {
+			byStation[f.Station] = append(byStation[f.Station], f)
+		}
+	}
+	kept := []ObsRoadweather{}
+	for _, obs := range obses {
+		if !(SeriesReport{byStation[obs.Station]}).Flagged(obs.Station, obs.RefTime) {
+			kept = append(kept, obs)
+		}
+	}
+	return kept
+}
+
+func (r SeriesReport) Sample(obses []ObsRoadweather) []ObsRoadweather {
+	return r.Exclude(obses)
+}
+
+// AnalyzeSeries checks the ice, water film and snow series of each station in
+// obses. The observations of a station may come once per camera.
+func AnalyzeSeries(obses []ObsRoadweather, checks SeriesChecks) SeriesReport {
+	type point struct {
+		t time.Time
+		v float32
+	}
+	type key struct {
+		station string
+		element string
+	}
+	series := make(map[key][]point)
+	seen := make(map[key]map[time.Time]bool)
+	for _, obs := range obses {
+		for _, e := range []struct {
+			bit  ElementMask
+			name string
+			v    float32
+		}{
+			{IcePresent, "road_ice_thickness", obs.IceThickness},
+			{WaterFilmPresent, "road_water_film_thickness", obs.WaterFilmThickness},
+			{SnowPresent, "road_snow_thickness", obs.SnowThickness},
+		} {
+			if obs.Present&e.bit == 0 {
+				continue
+			}
+			k := key{obs.Station, e.name}
+			if seen[k] == nil {
+				seen[k] = make(map[time.Time]bool)
+			}
+			if seen[k][obs.RefTime] {
+				continue
+			}
+			seen[k][obs.RefTime] = true
+			series[k] = append(series[k], point{obs.RefTime, e.v})
+		}
+	}
+
+	report := SeriesReport{Flags: []Flag{}}
+	for k, points := range series {
+		sort.Slice(points, func(i, j int) bool { return points[i].t.Before(points[j].t) })
+		flag := func(kind FlagKind, start, end time.Time, v float32) {
+			report.Flags = append(report.Flags, Flag{k.station, k.element, kind, start, end, v})
+		}
+
+		outOfRange := func(v float32) bool {
+			return (checks.Min != 0 || checks.Max != 0) && (v < checks.Min || v > checks.Max)
+		}
+		beyond := func(v float32) float32 {
+			if v < checks.Min {
+				return checks.Min - v
+			}
+			return v - checks.Max
+		}
+
+		runStart := 0
+		outStart := -1 // start of the current run of out of range values, flagged as one
+		var worst float32
+		for i, p := range points {
+			gap := i > 0 && checks.MaxGap > 0 && p.t.Sub(points[i-1].t) > checks.MaxGap
+			if outStart >= 0 && (gap || !outOfRange(p.v)) {
+				flag(FlagOutOfRange, points[outStart].t, points[i-1].t, worst)
+				outStart = -1
+			}
+			if outOfRange(p.v) {
+				if outStart < 0 {
+					outStart, worst = i, p.v
+				} else if beyond(p.v) > beyond(worst) {
+					worst = p.v
+				}
+			}
+			if i == 0 {
+				continue
+			}
+			prev := points[i-1]
+			if gap {
+				flag(FlagGap, prev.t, p.t, 0)
+			}
+			if checks.MaxJump > 0 && abs32(p.v-prev.v) > checks.MaxJump {
+				flag(FlagJump, prev.t, p.t, p.v-prev.v)
+			}
+			if p.v != points[runStart].v {
+				runStart = i
+			}
+			// Flag a run once, when it ends or at the end of the series
+			if i+1 < len(points) && points[i+1].v == p.v {
+				continue
+			}
+			start := points[runStart].t
+			if checks.MaxConstant > 0 && p.v != 0 && p.t.Sub(start) > checks.MaxConstant {
+				flag(FlagConstant, start, p.t, p.v)
+			}
+		}
+		if outStart >= 0 {
+			flag(FlagOutOfRange, points[outStart].t, points[len(points)-1].t, worst)
+		}
+	}
+
+	sort.Slice(report.Flags, func(i, j int) bool {
+		a, b := report.Flags[i], report.Flags[j]
+		if a.Station != b.Station {
+			return a.Station < b.Station
+		}
+		if a.Element != b.Element {
+			return a.Element < b.Element
+		}
+		return a.Start.Before(b.Start)
+	})
+	return report
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,112 @@
+package frostclient
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func flagStrings(flags []Flag) []string {
+	s := []string{}
+	for _, f := range flags {
+		s = append(s, fmt.Sprintf("%s %s %s-%s %g", f.Station, f.Kind, f.Start.Format("15:04"), f.End.Format("15:04"), f.Value))
+	}
+	return s
+}
+
+// iceSeries returns ice observations of station ten minutes apart, skipping
+// the NaN values.
+func iceSeries(station string, start time.Time, values ...float32) []ObsRoadweather {
+	obses := []ObsRoadweather{}
+	for i, v := range values {
+		if math.IsNaN(float64(v)) {
+			continue
+		}
+		obses = append(obses, ObsRoadweather{RefTime: start.Add(time.Duration(i) * 10 * time.Minute), Station: station, IceThickness: v, Present: IcePresent})
+	}
+	return obses
+}
+
+func TestAnalyzeSeries(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	nan := float32(math.NaN())
+	tests := []struct {
+		name   string
+		checks SeriesChecks
+		values []float32
+		want   []string
+	}{
+		{"clean", DefaultSeriesChecks, []float32{0, 0.1, 0.2, 0}, []string{}},
+		{"out of range run", SeriesChecks{Max: 10}, []float32{1, 20, 50, 30, 1, -1}, []string{
+			"SN1:0 out-of-range 00:10-00:30 50", "SN1:0 out-of-range 00:50-00:50 -1",
+		}},
+		{"out of range at the end", SeriesChecks{Max: 10}, []float32{1, 20, 11}, []string{"SN1:0 out-of-range 00:10-00:20 20"}},
+		{"out of range split by a gap", SeriesChecks{Max: 10, MaxGap: 30 * time.Minute}, []float32{20, nan, nan, nan, 30}, []string{
+			"SN1:0 out-of-range 00:00-00:00 20", "SN1:0 gap 00:00-00:40 0", "SN1:0 out-of-range 00:40-00:40 30",
+		}},
+		{"range off", SeriesChecks{}, []float32{-1, 200}, []string{}},
+		{"gap", SeriesChecks{MaxGap: 20 * time.Minute}, []float32{0, nan, 0, nan, nan, 0}, []string{"SN1:0 gap 00:20-00:50 0"}},
+		{"jump", SeriesChecks{MaxJump: 5}, []float32{0, 6, 7, 1}, []string{"SN1:0 jump 00:00-00:10 6", "SN1:0 jump 00:20-00:30 -6"}},
+		{"constant", SeriesChecks{MaxConstant: 20 * time.Minute}, []float32{0, 0, 0, 0, 2, 2, 2, 2, 3, 3}, []string{"SN1:0 constant 00:40-01:10 2"}},
+		{"constant at the end", SeriesChecks{MaxConstant: 20 * time.Minute}, []float32{1, 2, 2, 2, 2}, []string{"SN1:0 constant 00:10-00:40 2"}},
+	}
+	for _, tt := range tests {
+		report := AnalyzeSeries(iceSeries("SN1:0", start, tt.values...), tt.checks)
+		if got := flagStrings(report.Flags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeSeriesCameras(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	// The observations of a station come once per camera
+	obses := []ObsRoadweather{}
+	for _, cam := range []int{1, 2} {
+		for _, obs := range iceSeries("SN1:0", start, 1, 20, 1) {
+			obs.CamID = cam
+			obses = append(obses, obs)
+		}
+	}
+	obses = append(obses, ObsRoadweather{RefTime: start, Station: "SN1:0", SnowThickness: 200, Present: SnowPresent})
+	report := AnalyzeSeries(obses, SeriesChecks{Max: 10})
+	want := []Flag{
+		{"SN1:0", "road_ice_thickness", FlagOutOfRange, start.Add(10 * time.Minute), start.Add(10 * time.Minute), 20},
+		{"SN1:0", "road_snow_thickness", FlagOutOfRange, start, start, 200},
+	}
+	if !reflect.DeepEqual(report.Flags, want) {
+		t.Errorf("flags %v, want %v", report.Flags, want)
+	}
+}
+
+func TestSeriesReportExclude(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	at := func(m int) time.Time { return start.Add(time.Duration(m) * time.Minute) }
+	report := SeriesReport{Flags: []Flag{
+		{"SN1:0", "road_ice_thickness", FlagOutOfRange, at(10), at(20), 50},
+		{"SN1:0", "road_ice_thickness", FlagGap, at(30), at(60), 0},
+		{"SN2:0", "road_snow_thickness", FlagConstant, at(0), at(50), 2},
+	}}
+	obses := append(iceSeries("SN1:0", start, 0, 0, 0, 0, 0, 0, 0), iceSeries("SN2:0", start, 0, 0, 0, 0, 0, 0, 0)...)
+	kept := make(map[string][]string)
+	for _, obs := range report.Exclude(obses) {
+		kept[obs.Station] = append(kept[obs.Station], obs.RefTime.Format("15:04"))
+	}
+	want := map[string][]string{"SN1:0": {"00:00", "00:30", "00:40", "00:50", "01:00"}, "SN2:0": {"01:00"}}
+	if !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+	if !reflect.DeepEqual(report.Sample(obses), report.Exclude(obses)) {
+		t.Errorf("Sample and Exclude differ")
+	}
+
+	s := report.String()
+	for _, line := range []string{"3 flags at 2 stations", "SN1:0: gap 1, out-of-range 1\n", "SN2:0: constant 1\n"} {
+		if !strings.Contains(s, line) {
+			t.Errorf("report has no %q:\n%s", line, s)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package frostclient
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	_ "time/tzdata" // Europe/Oslo without a zoneinfo database on the host
+)
+
+// Oslo is the time zone of the Norwegian stations.
+var Oslo = loadLocation("Europe/Oslo")
+
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("time.LoadLocation(%s) failed, using UTC: %v", name, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// EveryNMinutesIn keeps the observations at multiples of n minutes after
+// local midnight in loc. n must be positive.
+func EveryNMinutesIn(loc *time.Location, n int) (Sampler, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("EveryNMinutes: n must be positive, got %d", n)
+	}
+	return described{SamplerFunc(func(obs ObsRoadweather) bool {
+		t := obs.RefTime.In(loc)
+		return (t.Hour()*60+t.Minute())%n == 0
+	}), fmt.Sprintf("EveryNMinutes(%s, %d)", loc, n), 0}, nil
+}
+
+// FixedHoursIn keeps the observations at minute 0 of hours local time in
+// loc, so FixedHoursIn(Oslo, 12) is noon in both winter and summer.
+func FixedHoursIn(loc *time.Location, hours ...int) Sampler {
+	set := make(map[int]bool, len(hours))
+	for _, h := range hours {
+		set[h] = true
+	}
+	return described{SamplerFunc(func(obs ObsRoadweather) bool {
+		t := obs.RefTime.In(loc)
+		return t.Minute() == 0 && set[t.Hour()]
+	}), fmt.Sprintf("FixedHours(%s, %v)", loc, hours), 0}
+}
+
+// LocalHours keeps the observations from hour from up to, not including, hour
+// to local time in loc. LocalHours(Oslo, 9, 15) keeps 09:00 to 14:50.
+func LocalHours(loc *time.Location, from, to int) Sampler {
+	return described{SamplerFunc(func(obs ObsRoadweather) bool {
+		h := obs.RefTime.In(loc).Hour()
+		return h >= from && h < to
+	}), fmt.Sprintf("LocalHours(%s, %d, %d)", loc, from, to), 0}
+}
+
+// SolarElevation returns the elevation of the sun in degrees above the horizon
+// at latitude lat and longitude lon at t. The approximation is good to about a
+// degree, which is enough to tell day from night.
+func SolarElevation(t time.Time, lat, lon float64) float64 {
+	rad := math.Pi / 180
+	d := float64(t.UTC().UnixNano())/float64(24*time.Hour) - 10957.5 // days since J2000.0
+
+	g := (357.529 + 0.98560028*d) * rad // mean anomaly
+	q := 280.459 + 0.98564736*d         // mean longitude
+	l := (q + 1.915*math.Sin(g) + 0.020*math.Sin(2*g)) * rad
+	e := (23.439 - 0.00000036*d) * rad // obliquity of the ecliptic
+
+	ra := math.Atan2(math.Cos(e)*math.Sin(l), math.Cos(l)) / rad
+	decl := math.Asin(math.Sin(e) * math.Sin(l))
+	gmst := 18.697374558 + 24.06570982441908*d // hours
+	h := (gmst*15 + lon - ra) * rad            // hour angle
+
+	return math.Asin(math.Sin(lat*rad)*math.Sin(decl)+math.Cos(lat*rad)*math.Cos(decl)*math.Cos(h)) / rad
+}
+
+// StationLocation returns the coordinates of station at t, from the Catalog
+// record valid then or else from the Frost sources fetched by GetSVVStations.
+func StationLocation(station string, t time.Time) (lat, lon float64, ok bool) {
+	if lat, lon, ok := CatalogLocation(station, t); ok {
+		return lat, lon, true
+	}
+	src, ok := svvSources[strings.Split(station, ":")[0]]
+	if !ok || len(src.Geometry.Coordinates) < 2 {
+		return 0, 0, false
+	}
+	return src.Geometry.Coordinates[1], src.Geometry.Coordinates[0], true
+}
+
+// CatalogLocation returns the coordinates of station at t from Catalog.
+func CatalogLocation(station string, t time.Time) (lat, lon float64, ok bool) {
+	if Catalog == nil {
+		return 0, 0, false
+	}
+	rec, ok := Catalog.ValidIn(station, t, t.Add(time.Nanosecond))
+	if !ok {
+		return 0, 0, false
+	}
+	return rec.Latitude, rec.Longitude, true
+}
+
+// Daylight keeps the observations made with the sun at least MinElevation
+// degrees above the horizon at the station. Locate gives the station
+// coordinates, StationLocation when nil. Observations of stations without
+// known coordinates are dropped.
+type Daylight struct {
+	MinElevation float64
+	Locate       func(station string, t time.Time) (lat, lon float64, ok bool)
+}
+
+func (d Daylight) String() string {
+	if d.Locate != nil {
+		return fmt.Sprintf("Daylight(%g, custom Locate)", d.MinElevation)
+	}
+	return fmt.Sprintf("Daylight(%g)", d.MinElevation)
+}
+
+func (d Daylight) Sample(obses []ObsRoadweather) []ObsRoadweather {
+	locate := d.Locate
+	if locate == nil {
+		locate = StationLocation
+	}
+	kept := []ObsRoadweather{}
+	unknown := make(map[string]bool)
+	for _, obs := range obses {
+		lat, lon, ok := locate(obs.Station, obs.RefTime)
+		if !ok {
+			unknown[obs.Station] = true
+			continue
+		}
+		if SolarElevation(obs.RefTime, lat, lon) >= d.MinElevation {
+			kept = append(kept, obs)
+		}
+	}
+	for s := range unknown {
+		log.Printf("Daylight: no coordinates for %s, dropping its observations", s)
+	}
+	return kept
+}
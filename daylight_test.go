@@ -0,0 +1,114 @@
+package frostclient
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSolarElevation(t *testing.T) {
+	tests := []struct {
+		name     string
+		t        time.Time
+		lat, lon float64
+		want     float64
+	}{
+		// At solar noon the elevation is 90 - latitude + declination
+		{"Oslo midsummer noon", time.Date(2023, 6, 21, 11, 17, 0, 0, time.UTC), 59.91, 10.75, 90 - 59.91 + 23.44},
+		{"Oslo midwinter noon", time.Date(2023, 12, 21, 11, 15, 0, 0, time.UTC), 59.91, 10.75, 90 - 59.91 - 23.44},
+		{"Oslo midwinter midnight", time.Date(2023, 12, 21, 23, 15, 0, 0, time.UTC), 59.91, 10.75, -90 + 59.91 - 23.44},
+		{"Tromsø polar night", time.Date(2023, 12, 21, 10, 50, 0, 0, time.UTC), 69.65, 18.96, 90 - 69.65 - 23.44},
+		{"Tromsø midnight sun", time.Date(2023, 6, 21, 22, 50, 0, 0, time.UTC), 69.65, 18.96, -90 + 69.65 + 23.44},
+		{"equator equinox noon", time.Date(2023, 3, 20, 12, 7, 0, 0, time.UTC), 0, 0, 90},
+	}
+	for _, tt := range tests {
+		if got := SolarElevation(tt.t, tt.lat, tt.lon); math.Abs(got-tt.want) > 1 {
+			t.Errorf("%s: SolarElevation = %.2f, want %.2f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocalHours(t *testing.T) {
+	winter := series(time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), time.Hour, 24, DryE, "SN1:0")
+	summer := series(time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC), time.Hour, 24, DryE, "SN1:0")
+	tests := []struct {
+		name  string
+		obses []ObsRoadweather
+		want  []string
+	}{
+		{"winter", winter, []string{"08:00", "09:00", "10:00", "11:00", "12:00", "13:00"}},
+		{"summer", summer, []string{"07:00", "08:00", "09:00", "10:00", "11:00", "12:00"}},
+	}
+	for _, tt := range tests {
+		if got := times(LocalHours(Oslo, 9, 15).Sample(tt.obses)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: LocalHours(Oslo, 9, 15) kept %v UTC, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDaylight(t *testing.T) {
+	locate := func(station string, t time.Time) (lat, lon float64, ok bool) {
+		switch station {
+		case "SN1:0":
+			return 59.91, 10.75, true // Oslo
+		case "SN2:0":
+			return 69.65, 18.96, true // Tromsø
+		}
+		return 0, 0, false
+	}
+	midwinter := time.Date(2023, 12, 21, 0, 0, 0, 0, time.UTC)
+	obses := series(midwinter, time.Hour, 24, DryE, "SN1:0", "SN2:0", "SN3:0")
+	kept := Daylight{MinElevation: 0, Locate: locate}.Sample(obses)
+	perStation := make(map[string][]string)
+	for _, obs := range kept {
+		perStation[obs.Station] = append(perStation[obs.Station], obs.RefTime.Format("15"))
+	}
+	// Sunrise in Oslo is about 08:20 UTC and sunset 14:15 UTC, Tromsø has polar night
+	want := map[string][]string{"SN1:0": {"09", "10", "11", "12", "13", "14"}}
+	if !reflect.DeepEqual(perStation, want) {
+		t.Errorf("kept hours %v, want %v", perStation, want)
+	}
+	if s := (Daylight{MinElevation: 5, Locate: locate}).String(); s != "Daylight(5, custom Locate)" {
+		t.Errorf("String = %q", s)
+	}
+}
+
+func TestStationLocation(t *testing.T) {
+	defer func(c *StationCatalog, sources map[string]Source) { Catalog, svvSources = c, sources }(Catalog, svvSources)
+	Catalog = &StationCatalog{Stations: map[string][]StationRecord{
+		"SN1": {
+			{ID: "SN1", ValidFrom: day(1), Latitude: 60, Longitude: 10},
+			{ID: "SN1", ValidFrom: day(10), Latitude: 61, Longitude: 11},
+		},
+	}}
+	svvSources = map[string]Source{
+		"SN1": source("SN1", 62, 12, 0),
+		"SN2": source("SN2", 63, 13, 0),
+		"SN3": {ID: "SN3"},
+	}
+	tests := []struct {
+		station  string
+		t        time.Time
+		lat, lon float64
+		ok       bool
+	}{
+		{"SN1:0", day(5), 60, 10, true},
+		{"SN1:0", day(15), 61, 11, true},
+		{"SN1:0", day(0), 62, 12, true}, // before the catalog
+		{"SN2:0", day(5), 63, 13, true},
+		{"SN3:0", day(5), 0, 0, false},
+		{"SN4:0", day(5), 0, 0, false},
+	}
+	for _, tt := range tests {
+		lat, lon, ok := StationLocation(tt.station, tt.t)
+		if lat != tt.lat || lon != tt.lon || ok != tt.ok {
+			t.Errorf("StationLocation(%s, %s) = %g, %g, %v, want %g, %g, %v", tt.station, tt.t.Format("2006-01-02"), lat, lon, ok, tt.lat, tt.lon, tt.ok)
+		}
+	}
+
+	Catalog = nil
+	if _, _, ok := CatalogLocation("SN1:0", day(5)); ok {
+		t.Errorf("CatalogLocation without a catalog found SN1:0")
+	}
+}
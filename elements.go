@@ -0,0 +1,139 @@
+package frostclient
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+var RoadweatherElements = []string{"road_ice_thickness", "road_water_film_thickness", "road_snow_thickness"}
+
+// Element is an item from /elements/v0.
+type Element struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Category    string `json:"category"`
+	Status      string `json:"status"`
+	URI         string `json:"uri"`
+}
+
+// GetElements returns the metadata of the elements in ids, keyed by ID.
+func GetElements(ids ...string) (map[string]Element, error) {
+	elements := make(map[string]Element)
+	url := fmt.Sprintf("https://frost.met.no/elements/v0.jsonld?ids=%s&lang=en-US", strings.Join(ids, ","))
+
+	resp, err := httpReq(url)
+	if err != nil {
+		return elements, fmt.Errorf("http.Get(%s) failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return elements, fmt.Errorf("http.Get(%s) Unexpected response code %d", url, resp.StatusCode)
+	}
+
+	res, drift, err := DecodeResponse[Element](resp.Body)
+	if err != nil {
+		return elements, err
+	}
+	logDrift(url, drift)
+
+	for _, e := range res.Data {
+		elements[e.ID] = e
+	}
+	return elements, nil
+}
+
+var mmPerUnit = map[string]float32{
+	"mm": 1,
+	"cm": 10,
+	"m":  1000,
+	"µm": 0.001, // micro sign
+	"μm": 0.001, // greek mu
+	"um": 0.001,
+}
+
+// ToMM converts value in unit to millimetres.
+func ToMM(value float32, unit string) (float32, error) {
+	f, ok := mmPerUnit[strings.TrimSpace(unit)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported unit: %q", unit)
+	}
+	return value * f, nil
+}
+
+// UnitReport counts the units seen per element. Unexpected are units other
+// than the one in the element metadata, Rejected the ones not convertible to
+// mm.
+type UnitReport struct {
+	Counts     map[string]int // "element unit" => values
+	Unexpected map[string]int
+	Rejected   map[string]int
+}
+
+func (r UnitReport) String() string {
+	var b strings.Builder
+	for _, m := range []struct {
+		name   string
+		counts map[string]int
+	}{{"Units", r.Counts}, {"Unexpected units", r.Unexpected}, {"Rejected units", r.Rejected}} {
+		keys := make([]string, 0, len(m.counts))
+		for k := range m.counts {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(&b, "%s:\n", m.name)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %s: %d\n", k, m.counts[k])
+		}
+	}
+	return b.String()
+}
+
+// UnitValidator converts observation values to mm and records the units in
+// Report.
+type UnitValidator struct {
+	Elements map[string]Element
+	Report   UnitReport
+}
+
+// NewUnitValidator loads the metadata of elements from Frost. If that fails
+// all elements are expected in mm.
+func NewUnitValidator(elements ...string) *UnitValidator {
+	meta, err := GetElements(elements...)
+	if err != nil {
+		log.Printf("GetElements: %v. Expecting mm", err)
+	}
+	return &UnitValidator{
+		Elements: meta,
+		Report: UnitReport{
+			Counts:     make(map[string]int),
+			Unexpected: make(map[string]int),
+			Rejected:   make(map[string]int),
+		},
+	}
+}
+
+// ToMM returns the value of obs in mm, false if the unit is not convertible.
+func (v *UnitValidator) ToMM(obs Observation) (float32, bool) {
+	key := obs.ElementID + " " + obs.Unit
+	v.Report.Counts[key]++
+
+	expected := "mm"
+	if e, ok := v.Elements[obs.ElementID]; ok && e.Unit != "" {
+		expected = e.Unit
+	}
+	if obs.Unit != expected {
+		v.Report.Unexpected[key]++
+	}
+
+	value, err := ToMM(obs.Value, obs.Unit)
+	if err != nil {
+		v.Report.Rejected[key]++
+		return 0, false
+	}
+	return value, true
+}
@@ -0,0 +1,71 @@
+package frostclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToMM(t *testing.T) {
+	tests := []struct {
+		value float32
+		unit  string
+		want  float32
+		ok    bool
+	}{
+		{1.5, "mm", 1.5, true},
+		{1.5, " mm ", 1.5, true},
+		{2, "cm", 20, true},
+		{0.001, "m", 1, true},
+		{500, "µm", 0.5, true},
+		{500, "μm", 0.5, true},
+		{500, "um", 0.5, true},
+		{1, "", 0, false},
+		{1, "inch", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ToMM(tt.value, tt.unit)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ToMM(%g, %q) = %g, %v, want %g, ok %v", tt.value, tt.unit, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUnitValidator(t *testing.T) {
+	v := &UnitValidator{
+		Elements: map[string]Element{"road_ice_thickness": {ID: "road_ice_thickness", Unit: "mm"}, "road_snow_thickness": {ID: "road_snow_thickness", Unit: "cm"}},
+		Report:   UnitReport{Counts: map[string]int{}, Unexpected: map[string]int{}, Rejected: map[string]int{}},
+	}
+	tests := []struct {
+		obs  Observation
+		want float32
+		ok   bool
+	}{
+		{Observation{ElementID: "road_ice_thickness", Value: 0.5, Unit: "mm"}, 0.5, true},
+		{Observation{ElementID: "road_ice_thickness", Value: 0.5, Unit: "cm"}, 5, true},
+		{Observation{ElementID: "road_snow_thickness", Value: 2, Unit: "cm"}, 20, true},
+		{Observation{ElementID: "road_water_film_thickness", Value: 0.1, Unit: "mm"}, 0.1, true},
+		{Observation{ElementID: "road_water_film_thickness", Value: 0.1, Unit: "ft"}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := v.ToMM(tt.obs)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ToMM(%g %s of %s) = %g, %v, want %g, %v", tt.obs.Value, tt.obs.Unit, tt.obs.ElementID, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	want := UnitReport{
+		Counts: map[string]int{
+			"road_ice_thickness mm": 1, "road_ice_thickness cm": 1, "road_snow_thickness cm": 1,
+			"road_water_film_thickness mm": 1, "road_water_film_thickness ft": 1,
+		},
+		Unexpected: map[string]int{"road_ice_thickness cm": 1, "road_water_film_thickness ft": 1},
+		Rejected:   map[string]int{"road_water_film_thickness ft": 1},
+	}
+	if !reflect.DeepEqual(v.Report, want) {
+		t.Errorf("report = %+v, want %+v", v.Report, want)
+	}
+	if s := v.Report.String(); !strings.Contains(s, "Rejected units:\n  road_water_film_thickness ft: 1\n") {
+		t.Errorf("report string is missing the rejected unit:\n%s", s)
+	}
+}
@@ -0,0 +1,243 @@
+package frostclient
+
+import (
+	"sort"
+	"time"
+)
+
+// Event is a period a station was in one class, or group of classes, from
+// the first to the last observation of it.
+type Event struct {
+	Station  string
+	CamID    int
+	Class    int // The group with EventOptions.Groups
+	Start    time.Time
+	End      time.Time
+	MaxIce   float32
+	MaxSnow  float32
+	MaxWater float32
+}
+
+func (e Event) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// EventGroups returns the groups of classes an observation is part of. The
+// events of a group may overlap those of another.
+type EventGroups func(obs ObsRoadweather) []int
+
+// ByElement groups observations by the elements on the road, ObsIce, ObsSnow
+// and ObsWater, so an icing event lasts as long as there is ice whatever the
+// class, e.g. through Ice, Wet+Ice and Ice again.
+var ByElement EventGroups = func(obs ObsRoadweather) []int {
+	groups := []int{}
+	if obs.IceThickness > 0 {
+		groups = append(groups, ObsIce)
+	}
+	if obs.SnowThickness > 0 {
+		groups = append(groups, ObsSnow)
+	}
+	if obs.WaterFilmThickness > 0 {
+		groups = append(groups, ObsWater)
+	}
+	return groups
+}
+
+func byClass(obs ObsRoadweather) []int {
+	return []int{obs.Class}
+}
+
+// EventOptions control ExtractEvents.
+type EventOptions struct {
+	// Groups finds events of groups of classes instead of single classes
+	// when set, e.g. ByElement for icing and snow cover events
+	Groups EventGroups
+	// Classes, or groups, to find events of, all when empty
+	Classes []int
+	// MaxGap is the longest time between two observations of an event,
+	// longer means the data is missing and the event ends. 0 => never.
+	MaxGap time.Duration
+	// MergeGap joins events of a class interrupted for at most this long
+	MergeGap time.Duration
+	// MinDuration drops shorter events after merging
+	MinDuration time.Duration
+}
+
+// DefaultEventOptions suit the 10 minute road weather observations.
+var DefaultEventOptions = EventOptions{
+	MaxGap:   time.Hour,
+	MergeGap: 30 * time.Minute,
+}
+
+// ExtractEvents finds the events in classified observations, per station and
+// camera, sorted by station, camera and start.
+func ExtractEvents(obses []ObsRoadweather, opts EventOptions) []Event {
+	groupsOf := opts.Groups
+	if groupsOf == nil {
+		groupsOf = byClass
+	}
+	wanted := make(map[int]bool, len(opts.Classes))
+	for _, c := range opts.Classes {
+		wanted[c] = true
+	}
+
+	sorted := make([]ObsRoadweather, len(obses))
+	copy(sorted, obses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Station != sorted[j].Station {
+			return sorted[i].Station < sorted[j].Station
+		}
+		if sorted[i].CamID != sorted[j].CamID {
+			return sorted[i].CamID < sorted[j].CamID
+		}
+		return sorted[i].RefTime.Before(sorted[j].RefTime)
+	})
+
+	events := []Event{}
+	open := make(map[int]*Event) // group => its event going on
+	end := func(group int) {
+		events = append(events, *open[group])
+		delete(open, group)
+	}
+	for i, obs := range sorted {
+		if i > 0 {
+			prev := sorted[i-1]
+			if obs.Station != prev.Station || obs.CamID != prev.CamID ||
+				(opts.MaxGap > 0 && obs.RefTime.Sub(prev.RefTime) > opts.MaxGap) {
+				for g := range open {
+					end(g)
+				}
+			}
+		}
+
+		in := make(map[int]bool)
+		for _, g := range groupsOf(obs) {
+			if len(wanted) == 0 || wanted[g] {
+				in[g] = true
+			}
+		}
+		for g := range open {
+			if !in[g] {
+				end(g)
+			}
+		}
+		for g := range in {
+			e, ok := open[g]
+			if !ok {
+				e = &Event{Station: obs.Station, CamID: obs.CamID, Class: g, Start: obs.RefTime}
+				open[g] = e
+			}
+			e.End = obs.RefTime
+			e.MaxIce = max32(e.MaxIce, obs.IceThickness)
+			e.MaxSnow = max32(e.MaxSnow, obs.SnowThickness)
+			e.MaxWater = max32(e.MaxWater, obs.WaterFilmThickness)
+		}
+	}
+	for g := range open {
+		end(g)
+	}
+	sortEvents(events)
+
+	return filterDuration(mergeEvents(events, opts.MergeGap), opts.MinDuration)
+}
+
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.Station != b.Station {
+			return a.Station < b.Station
+		}
+		if a.CamID != b.CamID {
+			return a.CamID < b.CamID
+		}
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		return a.Class < b.Class
+	})
+}
+
+// mergeEvents joins the events of a station, camera and class, or group, at
+// most gap apart. events must be sorted by station, camera and start.
+func mergeEvents(events []Event, gap time.Duration) []Event {
+	if gap <= 0 {
+		return events
+	}
+	merged := []Event{}
+	open := make(map[int]int) // class => index in merged, for the current station
+	for _, e := range events {
+		if len(merged) > 0 {
+			prev := merged[len(merged)-1]
+			if prev.Station != e.Station || prev.CamID != e.CamID {
+				open = make(map[int]int)
+			}
+		}
+		if i, ok := open[e.Class]; ok && e.Start.Sub(merged[i].End) <= gap {
+			m := &merged[i]
+			m.End = e.End
+			m.MaxIce = max32(m.MaxIce, e.MaxIce)
+			m.MaxSnow = max32(m.MaxSnow, e.MaxSnow)
+			m.MaxWater = max32(m.MaxWater, e.MaxWater)
+			continue
+		}
+		merged = append(merged, e)
+		open[e.Class] = len(merged) - 1
+	}
+
+	// The events interrupting a merged one are kept, so restore the order
+	sortEvents(merged)
+	return merged
+}
+
+func filterDuration(events []Event, min time.Duration) []Event {
+	if min <= 0 {
+		return events
+	}
+	kept := []Event{}
+	for _, e := range events {
+		if e.Duration() >= min {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// Onsets returns the observations of each event's class, or group, within
+// the first within of it, for picking the images from the start of the
+// events. groups must be the EventOptions.Groups the events were found with.
+func Onsets(events []Event, obses []ObsRoadweather, within time.Duration, groups EventGroups) []ObsRoadweather {
+	if groups == nil {
+		groups = byClass
+	}
+	type key struct {
+		station string
+		camID   int
+		class   int
+	}
+	byKey := make(map[key][]Event)
+	for _, e := range events {
+		k := key{e.Station, e.CamID, e.Class}
+		byKey[k] = append(byKey[k], e)
+	}
+
+	onsets := []ObsRoadweather{}
+	for _, obs := range obses {
+	groups:
+		for _, g := range groups(obs) {
+			for _, e := range byKey[key{obs.Station, obs.CamID, g}] {
+				if !obs.RefTime.Before(e.Start) && obs.RefTime.Sub(e.Start) <= within {
+					onsets = append(onsets, obs)
+					break groups
+				}
+			}
+		}
+	}
+	return onsets
+}
+
+func max32(a, b float32) float32 {
+	if b > a {
+		return b
+	}
+	return a
+}
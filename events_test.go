@@ -0,0 +1,99 @@
+package frostclient
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func eventStrings(events []Event) []string {
+	s := []string{}
+	for _, e := range events {
+		s = append(s, fmt.Sprintf("%s/%d %d %s-%s", e.Station, e.CamID, e.Class, e.Start.Format("15:04"), e.End.Format("15:04")))
+	}
+	return s
+}
+
+func TestExtractEvents(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	obses := classSeries("SN1:0", start, DryE, IceE, IceE, DryE, IceE, IceE, IceE, DryE, DryE, DryE, DryE, SnowE)
+	tests := []struct {
+		name string
+		opts EventOptions
+		want []string
+	}{
+		{"all classes", EventOptions{}, []string{
+			"SN1:0/0 0 00:00-00:00", "SN1:0/0 3 00:10-00:20", "SN1:0/0 0 00:30-00:30",
+			"SN1:0/0 3 00:40-01:00", "SN1:0/0 0 01:10-01:40", "SN1:0/0 2 01:50-01:50",
+		}},
+		{"one class", EventOptions{Classes: []int{IceE}}, []string{"SN1:0/0 3 00:10-00:20", "SN1:0/0 3 00:40-01:00"}},
+		{"merged", EventOptions{Classes: []int{IceE}, MergeGap: 20 * time.Minute}, []string{"SN1:0/0 3 00:10-01:00"}},
+		{"too far apart to merge", EventOptions{Classes: []int{IceE}, MergeGap: 10 * time.Minute}, []string{"SN1:0/0 3 00:10-00:20", "SN1:0/0 3 00:40-01:00"}},
+		{"merged around the interruption", EventOptions{MergeGap: 40 * time.Minute}, []string{
+			"SN1:0/0 0 00:00-01:40", "SN1:0/0 3 00:10-01:00", "SN1:0/0 2 01:50-01:50",
+		}},
+		{"minimum duration", EventOptions{MinDuration: 20 * time.Minute}, []string{"SN1:0/0 3 00:40-01:00", "SN1:0/0 0 01:10-01:40"}},
+	}
+	for _, tt := range tests {
+		if got := eventStrings(ExtractEvents(obses, tt.opts)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExtractEventsSeries(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	// A gap in the data ends an event, and so does another station or camera
+	gapped := append(classSeries("SN1:0", start, IceE, IceE), classSeries("SN1:0", start.Add(2*time.Hour), IceE, IceE)...)
+	cam2 := classSeries("SN1:0", start.Add(20*time.Minute), IceE)
+	cam2[0].CamID = 2
+	other := classSeries("SN2:0", start, IceE)
+	obses := append(append(append([]ObsRoadweather{}, other...), gapped...), cam2...)
+	tests := []struct {
+		name string
+		opts EventOptions
+		want []string
+	}{
+		{"no max gap", EventOptions{}, []string{"SN1:0/0 3 00:00-02:10", "SN1:0/2 3 00:20-00:20", "SN2:0/0 3 00:00-00:00"}},
+		{"max gap", EventOptions{MaxGap: time.Hour}, []string{
+			"SN1:0/0 3 00:00-00:10", "SN1:0/0 3 02:00-02:10", "SN1:0/2 3 00:20-00:20", "SN2:0/0 3 00:00-00:00",
+		}},
+	}
+	for _, tt := range tests {
+		if got := eventStrings(ExtractEvents(obses, tt.opts)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %v\nwant %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExtractEventsByElement(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	thickness := [][3]float32{ // ice, snow, water
+		{0, 0, 0},
+		{0.2, 0, 0},
+		{0.5, 0, 0.1},
+		{0.3, 1, 0},
+		{0, 2, 0},
+		{0, 0, 0},
+	}
+	obses := []ObsRoadweather{}
+	for i, th := range thickness {
+		obs := ObsRoadweather{RefTime: start.Add(time.Duration(i) * 10 * time.Minute), Station: "SN1:0", IceThickness: th[0], SnowThickness: th[1], WaterFilmThickness: th[2], Present: AllPresent}
+		obs.Class, _ = Scheme8.Classify(obs)
+		obses = append(obses, obs)
+	}
+	events := ExtractEvents(obses, EventOptions{Groups: ByElement})
+	want := []string{"SN1:0/0 2 00:10-00:30", "SN1:0/0 0 00:20-00:20", "SN1:0/0 1 00:30-00:40"}
+	if got := eventStrings(events); !reflect.DeepEqual(got, want) {
+		t.Errorf("events %v, want %v", got, want)
+	}
+	if events[0].MaxIce != 0.5 || events[2].MaxSnow != 2 || events[1].MaxWater != 0.1 {
+		t.Errorf("maxima %+v", events)
+	}
+
+	onsets := Onsets(events[:1], obses, 10*time.Minute, ByElement)
+	if got, want := times(onsets), []string{"00:10", "00:20"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("onsets of the icing at %v, want %v", got, want)
+	}
+}
@@ -5,7 +5,6 @@ package frostclient
 //package frostclient
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -20,129 +19,13 @@ import (
 
 var clientID = "e7413001-3139-4f82-8162-e2f1960ea7fb"
 
-type StationHolderReq struct {
-	Context          string    `json:"@context"`
-	Type             string    `json:"@type"`
-	APIVersion       string    `json:"apiVersion"`
-	License          string    `json:"license"`
-	CreatedAt        time.Time `json:"createdAt"`
-	QueryTime        float64   `json:"queryTime"`
-	CurrentItemCount int       `json:"currentItemCount"`
-	ItemsPerPage     int       `json:"itemsPerPage"`
-	Offset           int       `json:"offset"`
-	TotalItemCount   int       `json:"totalItemCount"`
-	CurrentLink      string    `json:"currentLink"`
-	Stations         []struct {
-		Type        string `json:"@type"`
-		ID          string `json:"id"`
-		Name        string `json:"name"`
-		ShortName   string `json:"shortName"`
-		Country     string `json:"country"`
-		CountryCode string `json:"countryCode"`
-		Geometry    struct {
-			Type        string    `json:"@type"`
-			Coordinates []float64 `json:"coordinates"`
-			Nearest     bool      `json:"nearest"`
-		} `json:"geometry"`
-		Masl           int       `json:"masl"`
-		ValidFrom      time.Time `json:"validFrom"`
-		County         string    `json:"county"`
-		CountyID       int       `json:"countyId"`
-		Municipality   string    `json:"municipality"`
-		MunicipalityID int       `json:"municipalityId"`
-		StationHolders []string  `json:"stationHolders"`
-		ExternalIds    []string  `json:"externalIds"`
-		WigosID        string    `json:"wigosId"`
-	} `json:"data"`
-}
-
-type Station struct {
-	Context          string    `json:"@context"`
-	Type             string    `json:"@type"`
-	APIVersion       string    `json:"apiVersion"`
-	License          string    `json:"license"`
-	CreatedAt        time.Time `json:"createdAt"`
-	QueryTime        float64   `json:"queryTime"`
-	CurrentItemCount int       `json:"currentItemCount"`
-	ItemsPerPage     int       `json:"itemsPerPage"`
-	Offset           int       `json:"offset"`
-	TotalItemCount   int       `json:"totalItemCount"`
-	CurrentLink      time.Time `json:"currentLink"`
-	Data             []struct {
-		SourceID      string    `json:"sourceId"`
-		ReferenceTime time.Time `json:"referenceTime"`
-		Observations  []struct {
-			ElementID           string  `json:"elementId"`
-			Value               float64 `json:"value"`
-			Unit                string  `json:"unit"`
-			TimeOffset          string  `json:"timeOffset"`
-			TimeResolution      string  `json:"timeResolution"`
-			TimeSeriesID        int     `json:"timeSeriesId"`
-			PerformanceCategory string  `json:"performanceCategory"`
-			ExposureCategory    string  `json:"exposureCategory"`
-			QualityCode         int     `json:"qualityCode"`
-		} `json:"observations"`
-	} `json:"data"`
-}
-
-type ObsType struct {
-	Context          string    `json:"@context"`
-	Type             string    `json:"@type"`
-	APIVersion       string    `json:"apiVersion"`
-	License          string    `json:"license"`
-	CreatedAt        time.Time `json:"createdAt"`
-	QueryTime        float64   `json:"queryTime"`
-	CurrentItemCount int       `json:"currentItemCount"`
-	ItemsPerPage     int       `json:"itemsPerPage"`
-	Offset           int       `json:"offset"`
-	TotalItemCount   int       `json:"totalItemCount"`
-	CurrentLink      string    `json:"currentLink"`
-	Data             []struct {
-		SourceID            string    `json:"sourceId"`
-		ValidFrom           time.Time `json:"validFrom"`
-		TimeOffset          string    `json:"timeOffset"`
-		TimeResolution      string    `json:"timeResolution"`
-		TimeSeriesID        int       `json:"timeSeriesId"`
-		ElementID           string    `json:"elementId"`
-		Unit                string    `json:"unit"`
-		PerformanceCategory string    `json:"performanceCategory"`
-		ExposureCategory    string    `json:"exposureCategory"`
-		Status              string    `json:"status"`
-		URI                 string    `json:"uri"`
-	} `json:"data"`
-}
-
-type ObsReq struct {
-	Context          string    `json:"@context"`
-	Type             string    `json:"@type"`
-	APIVersion       string    `json:"apiVersion"`
-	License          string    `json:"license"`
-	CreatedAt        time.Time `json:"createdAt"`
-	QueryTime        float64   `json:"queryTime"`
-	CurrentItemCount int       `json:"currentItemCount"`
-	ItemsPerPage     int       `json:"itemsPerPage"`
-	Offset           int       `json:"offset"`
-	TotalItemCount   int       `json:"totalItemCount"`
-	CurrentLink      string    `json:"currentLink"`
-	Data             []struct {
-		SourceID      string    `json:"sourceId"`
-		ReferenceTime time.Time `json:"referenceTime"`
-		Observations  []struct {
-			ElementID           string  `json:"elementId"`
-			Value               float32 `json:"value"`
-			Unit                string  `json:"unit"`
-			TimeOffset          string  `json:"timeOffset"`
-			TimeResolution      string  `json:"timeResolution"`
-			TimeSeriesID        int     `json:"timeSeriesId"`
-			PerformanceCategory string  `json:"performanceCategory"`
-			ExposureCategory    string  `json:"exposureCategory"`
-			QualityCode         int     `json:"qualityCode"`
-		} `json:"observations"`
-	} `json:"data"`
-}
-
+// obsTypeReq lists the road weather timeseries of frostID, of all sources
+// when empty.
 func obsTypeReq(frostID string) (ObsType, error) {
-	url := fmt.Sprintf("https://frost.met.no/observations/availableTimeSeries/v0.jsonld?sources=%s&elements=road_water_film_thickness,road_ice_thickness,road_snow_thickness&timeresolutions=PT10M", frostID)
+	url := fmt.Sprintf("https://frost.met.no/observations/availableTimeSeries/v0.jsonld?elements=%s&timeresolutions=%s", strings.Join(RoadweatherElements, ","), strings.Join(DefaultObsQuery.TimeResolutions, ","))
+	if frostID != "" {
+		url += "&sources=" + frostID
+	}
 	sh := ObsType{}
 
 	client := http.Client{
@@ -166,10 +49,11 @@ func obsTypeReq(frostID string) (ObsType, error) {
 		return sh, fmt.Errorf("http.Get(%s) Unexpected response code %d", url, resp.StatusCode)
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&sh)
+	sh, drift, err := DecodeResponse[TimeSeries](resp.Body)
 	if err != nil {
 		return sh, err
 	}
+	logDrift(url, drift)
 
 	return sh, nil
 
@@ -189,10 +73,11 @@ func stationHolderReq(url string) (StationHolderReq, error) {
 	}
 	defer resp.Body.Close()
 
-	err = json.NewDecoder(resp.Body).Decode(&sh)
+	sh, drift, err := DecodeResponse[Source](resp.Body)
 	if err != nil {
 		return sh, err
 	}
+	logDrift(url, drift)
 
 	return sh, nil
 
@@ -200,43 +85,96 @@ func stationHolderReq(url string) (StationHolderReq, error) {
 
 var snMap = make(map[string]string)
 
+// GetStationsWithSensor returns the first matched camera for every Frost
+// source with ice, water film and snow sensors. See GetStationCameras.
 func GetStationsWithSensor() (map[string]db.Camera, error) {
-	camMap := make(map[string]db.Camera)
-
 	sourcesMap := make(map[string]db.Camera)
-	cams, err := db.GetCams()
+	stationCams, _, err := GetStationCameras(nil)
 	if err != nil {
-		return sourcesMap, fmt.Errorf("db.GetCams(): %v", err)
+		return sourcesMap, err
 	}
-	for c := 0; c < len(cams); c++ {
-		idParts := strings.Split(cams[c].ForeignID, "_")
-		stID := idParts[0]
-		camMap[stID] = cams[c]
+	for id, cams := range stationCams {
+		sourcesMap[id] = cams[0]
 	}
+	return sourcesMap, nil
+}
 
-	res, err := stationHolderReq("https://frost.met.no/sources/v0.jsonld?stationholder=STATENS+VEGVESEN")
+// svvSources are the sources last returned by GetSVVStations by ID.
+var svvSources = make(map[string]Source)
+
+// GetSVVStations returns the Frost sources with Statens vegvesen as station
+// holder valid at some point since StationsSince.
+func GetSVVStations() (StationHolderReq, error) {
+	res, err := stationHolderReq(svvStationsURL(StationsSince))
 	if err != nil {
-		return sourcesMap, fmt.Errorf("stationHolderReq(): %v", err)
+		return res, err
 	}
-	elements := ""
-	sensorCount := make(map[string]int)
+	for _, src := range res.Data {
+		svvSources[src.ID] = src
+	}
+	return res, nil
+}
 
-	for s := 0; s < len(res.Stations); s++ {
-		ok := false
-		extid := ""
-		for f := 0; f < len(res.Stations[s].ExternalIds); f++ {
-			extid = res.Stations[s].ExternalIds[f]
-			_, ok = camMap[extid]
-			if ok {
-				break
+func svvStationsURL(from time.Time) string {
+	return fmt.Sprintf("https://frost.met.no/sources/v0.jsonld?stationholder=STATENS+VEGVESEN&validtime=%s/now", from.UTC().Format("2006-01-02"))
+}
+
+// GetStationCameras matches the SVV stations in Frost with the cameras in the
+// roadlabels DB and returns the cameras for every source with ice, water film
+// and snow sensors, along with the match report. Matching by location is opt
+// in: the cameras the ID schemes leave unmatched are matched to the nearest
+// station with road sensors if locate is given, and stay unmatched if it is
+// nil.
+func GetStationCameras(locate CameraLocator, schemes ...IDScheme) (map[string][]db.Camera, MatchReport, error) {
+	cams, err := db.GetCams()
+	if err != nil {
+		return map[string][]db.Camera{}, MatchReport{}, fmt.Errorf("db.GetCams(): %v", err)
+	}
+
+	res, err := GetSVVStations()
+	if err != nil {
+		return map[string][]db.Camera{}, MatchReport{}, fmt.Errorf("stationHolderReq(): %v", err)
+	}
+
+	loadCatalog()
+	if Catalog != nil {
+		Catalog.Update(res.Data)
+		if Catalog.Path != "" {
+			err = Catalog.Save()
+			if err != nil {
+				log.Printf("Catalog.Save(): %v", err)
 			}
 		}
+	}
 
-		if !ok {
-			continue
+	report := MatchStations(res.Data, cams, schemes...)
+	if locate != nil && len(report.UnmatchedCameras) > 0 {
+		candidates, err := RoadweatherCandidates(res.Data)
+		if err != nil {
+			log.Printf("RoadweatherCandidates: %v. Not matching by location", err)
+		} else {
+			locations := []CameraLocation{}
+			for _, cam := range report.UnmatchedCameras {
+				if loc, ok := locate(cam); ok {
+					locations = append(locations, loc)
+				}
+			}
+			report.AddGeoMatches(MatchByLocation(locations, candidates, DefaultGeoOptions))
 		}
+	}
+	log.Printf("Station matching:\n%s", report)
+
+	return SourcesWithSensors(report), report, nil
+}
+
+// SourcesWithSensors returns the cameras of the matched stations that have
+// ice, water film and snow sensors, keyed by Frost source ID.
+func SourcesWithSensors(report MatchReport) map[string][]db.Camera {
+	sourcesMap := make(map[string][]db.Camera)
+	sensorCount := make(map[string]int)
 
-		obstypes, err := obsTypeReq(res.Stations[s].ID)
+	for _, match := range report.Matches {
+		obstypes, err := obsTypeReq(match.StationID)
 		if err != nil {
 			log.Printf("obsTypeReq: %v", err)
 			continue
@@ -246,31 +184,19 @@ func GetStationsWithSensor() (map[string]db.Camera, error) {
 			sensorCount[v.ElementID]++
 		}
 
-		if len(obstypes.Data) != 3 {
-			continue
-		}
-
 		if !hasElm(obstypes, "road_water_film_thickness") || !hasElm(obstypes, "road_snow_thickness") || !hasElm(obstypes, "road_ice_thickness") {
 			continue
 		}
-		if strings.Contains(res.Stations[s].ID, ":") {
-			sourcesMap[res.Stations[s].ID] = camMap[extid]
+		if strings.Contains(match.StationID, ":") {
+			sourcesMap[match.StationID] = match.Cameras
 		}
-		for ot := 0; ot < len(obstypes.Data); ot++ {
-
-			obstype := obstypes.Data[ot]
-			sourcesMap[obstype.SourceID] = camMap[extid]
-
-			fmt.Printf("**%s ", obstype.ElementID)
-			elements += obstype.ElementID + ","
+		for _, obstype := range obstypes.Data {
+			sourcesMap[obstype.SourceID] = match.Cameras
 		}
-		fmt.Printf("\n")
-		time.Sleep(time.Second * 2)
 	}
-	fmt.Printf("%+v\n\n", sourcesMap)
-	fmt.Printf("%+v\n\n", sensorCount)
+	log.Printf("%d sources with road sensors, timeseries by element: %v", len(sourcesMap), sensorCount)
 
-	return sourcesMap, nil
+	return sourcesMap
 }
 
 func hasElm(s ObsType, str string) bool {
@@ -302,13 +228,13 @@ func getStationsWithIceSensor_Road_Ice_Thickness() {
 		os.Exit(1)
 	}
 
-	for s := 0; s < len(res.Stations); s++ {
-		for f := 0; f < len(res.Stations[s].ExternalIds); f++ {
-			extid := res.Stations[s].ExternalIds[f]
+	for s := 0; s < len(res.Data); s++ {
+		for f := 0; f < len(res.Data[s].ExternalIds); f++ {
+			extid := res.Data[s].ExternalIds[f]
 			cam, ok := camMap[extid]
 			if ok { // Station has camera
 
-				obstypes, err := obsTypeReq(res.Stations[s].ID)
+				obstypes, err := obsTypeReq(res.Data[s].ID)
 				if err != nil {
 					log.Printf("obsTypeReq: %v", err)
 					continue
@@ -366,11 +292,10 @@ func httpReq(url string) (*http.Response, error) {
 	return response, err
 }
 
-func obsRequest(sources string, elements string, timespan string) (ObsReq, error) {
+func obsRequest(q ObsQuery) (ObsReq, error) {
 
-	url := fmt.Sprintf("https://frost.met.no/observations/v0.jsonld?sources=%s&referencetime=%s&elements=%s&timeoffsets=PT0H&timeresolutions=PT10M&timeseriesids=0&performancecategories=C&exposurecategories=2", sources, timespan, elements)
+	url := q.URL()
 	//fmt.Printf("RequesT: %s", url)
-	_ = url
 
 	sh := ObsReq{}
 
@@ -384,10 +309,12 @@ func obsRequest(sources string, elements string, timespan string) (ObsReq, error
 		return sh, fmt.Errorf("http.Get(%s) Unexpected response code %d", url, resp.StatusCode)
 	}
 
-	err = json.NewDecoder(resp.Body).Decode(&sh)
+	sh, drift, err := DecodeResponse[ObservationsAtRefTime](resp.Body)
 	if err != nil {
-		return sh, fmt.Errorf("obsRequest json.NewDecoder(resp.Body).Decode(&sh) failed: %v,", err)
+		return sh, fmt.Errorf("obsRequest DecodeResponse(resp.Body) failed: %v,", err)
 	}
+	logDrift(url, drift)
+	FrostAPIVersion = sh.APIVersion
 
 	return sh, nil
 }
@@ -409,6 +336,11 @@ type ObsRoadweather struct {
 	WaterFilmThickness float32
 	SnowThickness      float32
 	Class              int
+	Present            ElementMask // Elements with a value, missing ones are 0
+	// Timeseries each value came from, see SensorPriority
+	IceSensor       Sensor
+	WaterFilmSensor Sensor
+	SnowSensor      Sensor
 }
 
 const (
@@ -478,120 +410,24 @@ var ClassesMap3 = map[int]string{
 	2: "Snow+Ice+Wet,Wet+Ice,Wet+Snow",
 }
 
-func GetDataFromFrost4Classes() (map[int][]ObsRoadweather, error) {
-
-	sourcesMap, err := GetStationsWithSensor()
-	if err != nil {
-		log.Fatalf("GetStationsWithSensor: %v", err)
+// countAmounts counts the thickness values present in obs.
+func countAmounts(amounts map[string]int, obs ObsRoadweather) {
+	if obs.Present&IcePresent != 0 {
+		amounts[fmt.Sprintf("Ice-%0.2f", obs.IceThickness)]++
 	}
-
-	keys := maps.Keys(sourcesMap)
-	sources := strings.Join(keys, ",")
-	fmt.Printf("Sources: %v\n", sourcesMap)
-
-	// This is so long back we have image data
-	start := time.Date(2023, 2, 10, 0, 0, 0, 00, time.UTC)
-	stop := time.Now().UTC()
-	from := start
-	to := start.Add(24 * time.Hour)
-	count := 0
-	maxdays := stop.Sub(start).Hours() / 24
-	log.Printf("Samples from %.0f days: ", maxdays)
-
-	classesCount := map[string]int{
-		"Dry":                   0,
-		"Wet":                   0,
-		"SnowAndOrIceNoWater":   0,
-		"SnowAndOrIceWithWater": 0,
+	if obs.Present&WaterFilmPresent != 0 {
+		amounts[fmt.Sprintf("Water-%0.2f", obs.WaterFilmThickness)]++
 	}
-	precipitationAmounts := map[string]int{}
-	class2Obses := make(map[int][]ObsRoadweather)
-
-	for from.Before(stop) {
-		log.Printf("Getting obs batch %d of %0.0f .. ", count, maxdays)
-		count++
-		//2023-02-10T00:00:00Z
-		timespan := fmt.Sprintf("%s/%s", from.Format("2006-01-02T15:04Z"), to.Format("2006-01-02T15:04Z"))
-		//timespan := "2023-02-10T00:00Z/2023-02-11T00:00Z"
-		resp, err := obsRequest(sources, "road_ice_thickness,road_water_film_thickness,road_snow_thickness", timespan)
-		if err != nil {
-			log.Printf("GetDataFromFrost obsRequest: httpresp: %v error: %v", resp, err)
-			count--
-			continue
-		}
-
-		times := resp.Data
-
-		for t := 0; t < len(times); t++ {
-			roadConditionClass := -1
-
-			if times[t].ReferenceTime.Hour() != 0 && times[t].ReferenceTime.Hour() != 6 && times[t].ReferenceTime.Hour() != 12 && times[t].ReferenceTime.Hour() != 18 { // forEach 6th hour
-				continue
-			}
-			if times[t].ReferenceTime.UTC().Minute() != 0 {
-				continue
-			}
-			// These are "Guaranteed" to be overwritten
-			var iceThickness float32 = 0.0
-			var waterThickness float32 = 0.0
-			var snowThickness float32 = 0.0
-			for o := 0; o < len(times[t].Observations); o++ {
-				if times[t].Observations[o].Unit != "mm" { // Just in case ..
-					log.Printf("GetFrostObses() Unsupported unit: %s", times[t].Observations[o].Unit)
-					continue
-				}
-				if times[t].Observations[o].ElementID == "road_ice_thickness" {
-					iceThickness = times[t].Observations[o].Value
-					k := fmt.Sprintf("Ice-%0.2f", iceThickness)
-					precipitationAmounts[k]++
-
-				}
-				if times[t].Observations[o].ElementID == "road_water_film_thickness" {
-					waterThickness = times[t].Observations[o].Value
-					k := fmt.Sprintf("Water-%0.2f", waterThickness)
-					precipitationAmounts[k]++
-				}
-				if times[t].Observations[o].ElementID == "road_snow_thickness" {
-					snowThickness = times[t].Observations[o].Value
-					k := fmt.Sprintf("Snow-%0.2f", snowThickness)
-					precipitationAmounts[k]++
-				}
-			}
-
-			if iceThickness == 0.0 && waterThickness == 0.0 && snowThickness == 0.0 {
-				roadConditionClass = Dry4
-				classesCount["Dry"]++
-
-			} else if iceThickness == 0.0 && snowThickness == 0.0 && waterThickness > 0.0 {
-				roadConditionClass = Wet4
-				classesCount["Wet"]++
-			} else if (iceThickness > 0.0 || snowThickness > 0.0) && waterThickness == 0.0 {
-				roadConditionClass = SnowAndOrIceNoWater4
-				classesCount["SnowAndOrIceNoWater"]++
-			} else if (iceThickness > 0.0 || snowThickness > 0.0) && waterThickness > 0.0 {
-				roadConditionClass = SnowAndOrIceWithWather4
-				classesCount["SnowAndOrIceWithWater"]++
-
-			} else {
-				panic("Logic error")
-			}
-
-			obs := ObsRoadweather{}
-			obs.CamID = sourcesMap[times[t].SourceID].ID
-			obs.Station = times[t].SourceID
-			obs.IceThickness = iceThickness
-			obs.WaterFilmThickness = waterThickness
-			obs.SnowThickness = snowThickness
-			obs.RefTime = times[t].ReferenceTime.UTC()
-			obs.Class = roadConditionClass
-
-			class2Obses[roadConditionClass] = append(class2Obses[roadConditionClass], obs)
-		}
-		from = from.Add(24 * time.Hour)
-		to = to.Add(24 * time.Hour)
+	if obs.Present&SnowPresent != 0 {
+		amounts[fmt.Sprintf("Snow-%0.2f", obs.SnowThickness)]++
 	}
+}
 
-	log.Printf("\nClasscount: %+v\n", classesCount)
+// printAmounts prints the number of observations of each thickness value.
+// fetchClassified counts every parsed observation once per source, before
+// sampling. Before the samplers the count was of the observations left by
+// the hour filters of each GetDataFromFrost function.
+func printAmounts(precipitationAmounts map[string]int) {
 	ky := make([]string, 0, len(precipitationAmounts))
 	for k := range precipitationAmounts {
 		ky = append(ky, k)
@@ -600,48 +436,109 @@ func GetDataFromFrost4Classes() (map[int][]ObsRoadweather, error) {
 	for _, k := range ky {
 		fmt.Println(k, precipitationAmounts[k])
 	}
+}
 
-	return class2Obses, nil
+// Build configures how the GetDataFromFrost functions build their datasets.
+// The zero value builds them as the package functions of the same names do.
+type Build struct {
+	// Smooth is applied to the whole classified series of each station
+	// before sampling when set. The unsampled series of all stations is then
+	// held in memory until the last day is fetched.
+	Smooth *Smoothing
+	// SeriesQuality is checked on the unsampled series of each station when
+	// set, and the observations in flagged periods are left out before
+	// smoothing and sampling. Like Smooth it holds the whole series in memory.
+	SeriesQuality *SeriesChecks
+	// Rebuild is the snapshot of a dataset to build again when set, see
+	// Snapshot.Build. Its stations, skip list, stop, query, quality policy,
+	// sensor priority and RefuseIncomplete are used instead of the package
+	// configuration, and the build fails if the scheme, sampler, period or
+	// checks differ from the ones recorded.
+	Rebuild *Snapshot
 }
 
-func GetDataFromFrost6Classes() (map[int][]ObsRoadweather, error) {
+// buildSettings is the configuration a build fetches and classifies with.
+type buildSettings struct {
+	query            ObsQuery
+	qualityPolicy    QualityPolicy
+	sensorPriority   SensorPriority
+	refuseIncomplete bool
+}
 
-	sourcesMap, err := GetStationsWithSensor()
-	if err != nil {
-		log.Fatalf("GetStationsWithSensor: %v", err)
+// settings returns the configuration recorded in b.Rebuild, or the package
+// configuration.
+func (b Build) settings() buildSettings {
+	if b.Rebuild == nil {
+		return buildSettings{DefaultObsQuery, DefaultQualityPolicy, DefaultSensorPriority, RefuseIncomplete}
 	}
+	q := b.Rebuild.Query
+	q.Sources, q.ReferenceTime = nil, ""
+	return buildSettings{q, b.Rebuild.QualityPolicy, b.Rebuild.SensorPriority, b.Rebuild.RefuseIncomplete}
+}
 
+// stop returns the end of the datasets running until now, see DatasetStop.
+func (b Build) stop() time.Time {
+	if b.Rebuild != nil {
+		return b.Rebuild.Stop
+	}
+	return datasetStop()
+}
+
+// fetchClassified requests the observations of the sources in sourcesMap one
+// day at a time from start to stop, classifies them by scheme and returns the
+// ones kept by sampler, one for each camera of the source, and the thickness
+// amounts of all, see printAmounts. Sources in skip are left out.
+func (b Build) fetchClassified(sourcesMap map[string][]db.Camera, scheme Scheme, sampler Sampler, start, stop time.Time, skip map[string]bool) ([]ObsRoadweather, map[string]int) {
 	keys := maps.Keys(sourcesMap)
-	sources := strings.Join(keys, ",")
-	fmt.Printf("Sources: %v\n", sourcesMap)
+	settings := b.settings()
+	units := NewUnitValidator(RoadweatherElements...)
+	quality := NewQualityFilter(settings.qualityPolicy)
+	incomplete := make(map[string]int)
 
-	// This is so long back we have image data
-	start := time.Date(2023, 2, 10, 0, 0, 0, 00, time.UTC)
-	stop := time.Now().UTC()
 	from := start
 	to := start.Add(24 * time.Hour)
 	count := 0
 	maxdays := stop.Sub(start).Hours() / 24
 	log.Printf("Samples from %.0f days: ", maxdays)
 
-	classesCount := map[string]int{
-		"Dry":                           0,
-		"Wet":                           0,
-		"Snow":                          0,
-		"Ice":                           0,
-		"Snow+Ice":                      0,
-		"Snow+Ice+Wet,Wet+Ice,Wet+Snow": 0,
+	classesCount := make(map[string]int)
+	for _, name := range scheme.Classes {
+		classesCount[name] = 0
+	}
+	obses := []ObsRoadweather{}
+	amounts := make(map[string]int)
+	// The whole unsampled series is kept for smoothing and quality checks,
+	// with the observations that could not be classified as class -1
+	whole := b.Smooth != nil || b.SeriesQuality != nil
+	series := []ObsRoadweather{}
+
+	// keep adds the sampled observations once for each camera of the source
+	keep := func(sampled []ObsRoadweather) {
+		for _, obs := range sampled {
+			classesCount[scheme.Classes[obs.Class]]++
+			for _, cam := range sourcesMap[obs.Station] {
+				obs.CamID = cam.ID
+				obses = append(obses, obs)
+			}
+		}
 	}
 
-	class2Obses := make(map[int][]ObsRoadweather)
-
 	for from.Before(stop) {
 		log.Printf("Getting obs batch %d of %0.0f .. ", count, maxdays)
 		count++
 		//2023-02-10T00:00:00Z
+		sources := validSources(keys, from, to)
+		if len(sources) == 0 {
+			from = from.Add(24 * time.Hour)
+			to = to.Add(24 * time.Hour)
+			continue
+		}
 		timespan := fmt.Sprintf("%s/%s", from.Format("2006-01-02T15:04Z"), to.Format("2006-01-02T15:04Z"))
 		//timespan := "2023-02-10T00:00Z/2023-02-11T00:00Z"
-		resp, err := obsRequest(sources, "road_ice_thickness,road_water_film_thickness,road_snow_thickness", timespan)
+		q := settings.query
+		q.Sources = sources
+		q.ReferenceTime = timespan
+		resp, err := obsRequest(q)
 		if err != nil {
 			log.Printf("GetDataFromFrost obsRequest: httpresp: %v error: %v", resp, err)
 			count--
@@ -649,535 +546,247 @@ func GetDataFromFrost6Classes() (map[int][]ObsRoadweather, error) {
 		}
 
 		times := resp.Data
+		batch := []ObsRoadweather{}
 
 		for t := 0; t < len(times); t++ {
-			roadConditionClass := -1
-
-			//if times[t].ReferenceTime.Hour() == 0 || times[t].ReferenceTime.Hour() == 6 || times[t].ReferenceTime.Hour() == 12 || times[t].ReferenceTime.Hour() == 18 { // forEach 6th hour
-
-			if times[t].ReferenceTime.UTC().Minute() != 0 {
+			if skip[times[t].SourceID] {
 				continue
 			}
 
-			var iceThickness float32 = 0.0
-			var waterThickness float32 = 0.0
-			var snowThickness float32 = 0.0
-			for o := 0; o < len(times[t].Observations); o++ {
-				if times[t].Observations[o].Unit != "mm" { // Just in case ..
-					log.Printf("GetFrostObses() Unsupported unit: %s", times[t].Observations[o].Unit)
-					continue
-				}
-				if times[t].Observations[o].ElementID == "road_ice_thickness" {
-					iceThickness = times[t].Observations[o].Value
-
-				}
-				if times[t].Observations[o].ElementID == "road_water_film_thickness" {
-					waterThickness = times[t].Observations[o].Value
-				}
-				if times[t].Observations[o].ElementID == "road_snow_thickness" {
-					snowThickness = times[t].Observations[o].Value
-				}
-			}
-
-			if iceThickness == 0.0 && waterThickness == 0.0 && snowThickness == 0.0 &&
-				(times[t].ReferenceTime.UTC().Hour() == 0 ||
-					times[t].ReferenceTime.UTC().Hour() == 12 ||
-					times[t].ReferenceTime.UTC().Hour() == 6 ||
-					times[t].ReferenceTime.UTC().Hour() == 18) {
-				roadConditionClass = Dry6
-				classesCount["Dry"]++
-
-			} else if iceThickness == 0.0 && waterThickness == 0.0 && snowThickness == 0.0 {
-				// Downsample Dry
-				continue
-			} else if iceThickness > 0.0 && waterThickness > 0.0 && snowThickness > 0.0 {
-				roadConditionClass = SnowAndIceAndWetOrWetAndIceOrWetAndSnow6
-				classesCount["Snow+Ice+Wet,Wet+Ice,Wet+Snow"]++
-			} else if iceThickness > 0.0 && snowThickness > 0.0 && waterThickness == 0.0 {
-				roadConditionClass = SnowAndIce6
-				classesCount["Snow+Ice"]++
-			} else if iceThickness > 0.0 && waterThickness > 0.0 && snowThickness == 0.0 {
-				roadConditionClass = SnowAndIceAndWetOrWetAndIceOrWetAndSnow6
-				classesCount["Snow+Ice+Wet,Wet+Ice,Wet+Snow"]++
-			} else if snowThickness > 0.0 && waterThickness > 0.0 && iceThickness == 0.0 {
-				roadConditionClass = SnowAndIceAndWetOrWetAndIceOrWetAndSnow6
-				classesCount["Snow+Ice+Wet,Wet+Ice,Wet+Snow"]++
-			} else if iceThickness > 0.0 { // => Ice only
-				roadConditionClass = Ice6
-				classesCount["Ice"]++
-			} else if snowThickness > 0.0 { // => Snow only
-				roadConditionClass = Snow6
-				classesCount["Snow"]++
-			} else if waterThickness > 0.0 { // => Wateronly
-				roadConditionClass = Wet6
-				classesCount["Wet"]++
-			} else {
-				panic("Logic error")
-			}
-
-			obs := ObsRoadweather{}
-			obs.CamID = sourcesMap[times[t].SourceID].ID
-			obs.Station = times[t].SourceID
-			obs.IceThickness = iceThickness
-			obs.WaterFilmThickness = waterThickness
-			obs.SnowThickness = snowThickness
-			obs.RefTime = times[t].ReferenceTime.UTC()
+			obs := parseRoadweather(times[t], units, quality, settings.sensorPriority)
+			countAmounts(amounts, obs)
+			roadConditionClass, ok := classify(scheme, obs, settings.refuseIncomplete, incomplete)
 			obs.Class = roadConditionClass
-			class2Obses[roadConditionClass] = append(class2Obses[roadConditionClass], obs)
+			if whole {
+				series = append(series, obs)
+			} else if ok {
+				batch = append(batch, obs)
+			}
 		}
+
+		keep(sampler.Sample(batch))
 		from = from.Add(24 * time.Hour)
 		to = to.Add(24 * time.Hour)
 	}
 
-	log.Printf("\nClasscount: %+v", classesCount)
-
-	return class2Obses, nil
-}
-
-func GetObsMapForLabelApp() (map[string][]ObsRoadweather, error) {
-
-	sourcesMap, err := GetStationsWithSensor()
-	if err != nil {
-		log.Fatalf("GetStationsWithSensor: %v", err)
+	if whole {
+		if b.SeriesQuality != nil {
+			report := AnalyzeSeries(series, *b.SeriesQuality)
+			log.Printf("%s", report)
+			series = report.Exclude(series)
+		}
+		classified := []ObsRoadweather{}
+		for _, obs := range series {
+			if obs.Class >= 0 {
+				classified = append(classified, obs)
+			}
+		}
+		if b.Smooth != nil {
+			// The whole series, so runs across midnight are measured in one piece
+			classified = b.Smooth.Apply(scheme, classified)
+		}
+		keep(sampler.Sample(classified))
 	}
 
-	keys := maps.Keys(sourcesMap)
-	sources := strings.Join(keys, ",")
-	fmt.Printf("Sources: %v\n", sourcesMap)
-
-	// This is so long back we have image data
-	start := time.Date(2023, 2, 10, 0, 0, 0, 00, time.UTC)
-	//start := time.Date(2021, 2, 10, 0, 0, 0, 00, time.UTC)
-	// No ice after .. may maybe?
-
-	//stop := time.Date(2023, 5, 12, 0, 0, 0, 00, time.UTC)
-	stop := time.Date(2023, 11, 16, 0, 0, 0, 00, time.UTC)
-	//stop := time.Date(2023, 2, 12, 0, 0, 0, 00, time.UTC)
+	log.Printf("\nClasscount: %+v\n", classesCount)
+	log.Printf("%s", units.Report)
+	log.Printf("%s", quality.Report)
+	log.Printf("Incomplete observations by elements present: %+v", incomplete)
 
-	from := start
-	to := start.Add(24 * time.Hour)
-	count := 0
-	maxdays := stop.Sub(start).Hours() / 24
-	log.Printf("Samples from %.0f days: ", maxdays)
+	return obses, amounts
+}
 
-	classesCount := map[string]int{
-		"Dry":          0,
-		"Wet":          0,
-		"Snow":         0,
-		"Ice":          0,
-		"Wet+Snow":     0,
-		"Wet+Ice":      0,
-		"Snow+Ice":     0,
-		"Snow+Ice+Wet": 0,
+func groupByClass(obses []ObsRoadweather) map[int][]ObsRoadweather {
+	class2Obses := make(map[int][]ObsRoadweather)
+	for _, obs := range obses {
+		class2Obses[obs.Class] = append(class2Obses[obs.Class], obs)
 	}
+	return class2Obses
+}
 
-	class2Obses := make(map[string][]ObsRoadweather)
-
-	for from.Before(stop) {
-		log.Printf("Getting obs batch %d of %0.0f .. ", count, maxdays)
-		count++
-		timespan := fmt.Sprintf("%s/%s", from.Format("2006-01-02T15:04Z"), to.Format("2006-01-02T15:04Z"))
-		resp, err := obsRequest(sources, "road_ice_thickness,road_water_film_thickness,road_snow_thickness", timespan)
-		if err != nil {
-			log.Printf("GetDataFromFrost obsRequest: httpresp: %v error: %v", resp, err)
-			count--
-			continue
+// buildDataset fetches the dataset of scheme from start to stop with
+// fetchClassified and records it in LastSnapshot. The stations are matched
+// with GetStationCameras, or taken from b.Rebuild along with its skip list.
+func (b Build) buildDataset(scheme Scheme, sampler Sampler, start, stop time.Time, skip map[string]bool) ([]ObsRoadweather, map[string]int, error) {
+	sourcesMap := make(map[string][]db.Camera)
+	if b.Rebuild != nil {
+		if err := b.Rebuild.rebuilds(b, scheme, sampler, start, stop); err != nil {
+			return nil, nil, fmt.Errorf("rebuilding snapshot: %v", err)
 		}
-
-		times := resp.Data
-
-		for t := 0; t < len(times); t++ {
-			roadConditionClass := -1
-
-			if times[t].ReferenceTime.UTC().Minute() != 0 {
-				continue
-			}
-
-			var iceThickness float32 = 0.0
-			var waterThickness float32 = 0.0
-			var snowThickness float32 = 0.0
-			for o := 0; o < len(times[t].Observations); o++ {
-				if times[t].Observations[o].Unit != "mm" { // Just in case ..
-					log.Printf("GetFrostObses() Unsupported unit: %s", times[t].Observations[o].Unit)
-					continue
-				}
-				if times[t].Observations[o].ElementID == "road_ice_thickness" {
-					iceThickness = times[t].Observations[o].Value
-
-				}
-				if times[t].Observations[o].ElementID == "road_water_film_thickness" {
-					waterThickness = times[t].Observations[o].Value
-				}
-				if times[t].Observations[o].ElementID == "road_snow_thickness" {
-					snowThickness = times[t].Observations[o].Value
-				}
-			}
-
-			if times[t].ReferenceTime.UTC().Hour() == 0 || times[t].ReferenceTime.UTC().Hour() == 12 ||
-				times[t].ReferenceTime.UTC().Hour() == 6 || times[t].ReferenceTime.UTC().Hour() == 18 {
-
-				if iceThickness == 0.0 && waterThickness == 0.0 && snowThickness == 0.0 {
-					roadConditionClass = DryE
-					classesCount["Dry"]++
-				} else if iceThickness > 0.0 && waterThickness > 0.0 && snowThickness > 0.0 {
-					roadConditionClass = SnowAndIceAndWetE
-					classesCount["Snow+Ice+Wet"]++
-				} else if iceThickness > 0.0 && snowThickness > 0.0 {
-					roadConditionClass = SnowAndIceE
-					classesCount["Snow+Ice"]++
-				} else if iceThickness > 0.0 && waterThickness > 0.0 {
-					roadConditionClass = WetAndIceE
-					classesCount["Wet+Ice"]++
-				} else if snowThickness > 0.0 && waterThickness > 0.0 {
-					roadConditionClass = WetAndSnowE
-					classesCount["Wet+Snow"]++
-				} else if iceThickness > 0.0 { // => Ice only
-					roadConditionClass = IceE
-					classesCount["Ice"]++
-				} else if snowThickness > 0.0 { // => Snow only
-					roadConditionClass = SnowE
-					classesCount["Snow"]++
-				} else if waterThickness > 0.0 { // => Wateronly
-					roadConditionClass = WetE
-					classesCount["Wet"]++
-				} else {
-					panic("Logic error")
-				}
-
-				obs := ObsRoadweather{}
-				obs.CamID = sourcesMap[times[t].SourceID].ID
-				obs.Station = times[t].SourceID
-				obs.IceThickness = iceThickness
-				obs.WaterFilmThickness = waterThickness
-				obs.SnowThickness = snowThickness
-				obs.RefTime = times[t].ReferenceTime.UTC()
-				obs.Class = roadConditionClass
-
-				if roadConditionClass == Dry {
-					class2Obses["Dry"] = append(class2Obses["Dry"], obs)
-				} else if roadConditionClass == WetE {
-					class2Obses["Water"] = append(class2Obses["Water"], obs)
-				} else if roadConditionClass == SnowE {
-					class2Obses["Snow"] = append(class2Obses["Snow"], obs)
-				} else if roadConditionClass == IceE {
-					class2Obses["Ice"] = append(class2Obses["Ice"], obs)
-				} else if roadConditionClass == WetAndSnowE {
-					class2Obses["Water+Snow"] = append(class2Obses["Water+Snow"], obs)
-				} else if roadConditionClass == WetAndIceE {
-					class2Obses["Water+Ice"] = append(class2Obses["Water+Ice"], obs)
-				} else if roadConditionClass == SnowAndIceE {
-					class2Obses["Snow+Ice"] = append(class2Obses["Snow+Ice"], obs)
-				} else if roadConditionClass == SnowAndIceAndWetE {
-					class2Obses["Snow+Ice+Water"] = append(class2Obses["Snow+Ice+Water"], obs)
-				}
+		if b.Rebuild.ClientVersion != Version {
+			log.Printf("Rebuilding snapshot made by client %s, this is %s", b.Rebuild.ClientVersion, Version)
+		}
+		for id, camIDs := range b.Rebuild.Stations {
+			for _, camID := range camIDs {
+				sourcesMap[id] = append(sourcesMap[id], db.Camera{ID: camID})
 			}
 		}
-		from = from.Add(24 * time.Hour)
-		to = to.Add(24 * time.Hour)
+		skip = make(map[string]bool)
+		for _, id := range b.Rebuild.Skip {
+			skip[id] = true
+		}
+	} else {
+		var err error
+		sourcesMap, _, err = GetStationCameras(nil)
+		if err != nil {
+			log.Fatalf("GetStationCameras: %v", err)
+		}
 	}
+	obses, amounts := b.fetchClassified(sourcesMap, scheme, sampler, start, stop, skip)
+
+	settings := b.settings()
+	q := settings.query
+	q.Sources = maps.Keys(sourcesMap)
+	s := NewSnapshot(scheme, q, start, stop)
+	s.RefuseIncomplete = settings.refuseIncomplete
+	s.QualityPolicy = settings.qualityPolicy
+	s.SensorPriority = settings.sensorPriority
+	for id, cams := range sourcesMap {
+		for _, cam := range cams {
+			s.Stations[id] = append(s.Stations[id], cam.ID)
+		}
+	}
+	for id := range skip {
+		s.Skip = append(s.Skip, id)
+	}
+	sort.Strings(s.Skip)
+	s.Smooth = b.Smooth
+	s.SeriesQuality = b.SeriesQuality
+	s.Sampler = describeSampler(sampler)
+	s.Seed = samplerSeed(sampler)
+	LastSnapshot = s
+	if SnapshotPath != "" {
+		if err := s.Write(SnapshotPath); err != nil {
+			return obses, amounts, err
+		}
+	}
+	return obses, amounts, nil
+}
 
-	log.Printf("\nClasscount: %+v", classesCount)
-
-	return class2Obses, nil
+func GetDataFromFrost4Classes() (map[int][]ObsRoadweather, error) {
+	return Build{}.GetDataFromFrost4Classes()
 }
 
-func GetDataFromFrost8Classes() (map[int][]ObsRoadweather, error) {
+func (b Build) GetDataFromFrost4Classes() (map[int][]ObsRoadweather, error) {
 
-	sourcesMap, err := GetStationsWithSensor()
+	// This is so long back we have image data
+	start := time.Date(2023, 2, 10, 0, 0, 0, 00, time.UTC)
+	stop := b.stop()
+
+	obses, amounts, err := b.buildDataset(Scheme4, Sampler4, start, stop, nil)
 	if err != nil {
-		log.Fatalf("GetStationsWithSensor: %v", err)
+		return nil, err
 	}
+	printAmounts(amounts)
 
-	keys := maps.Keys(sourcesMap)
-	sources := strings.Join(keys, ",")
-	fmt.Printf("Sources: %v\n", sourcesMap)
+	return groupByClass(obses), nil
+}
+
+func GetDataFromFrost6Classes() (map[int][]ObsRoadweather, error) {
+	return Build{}.GetDataFromFrost6Classes()
+}
+
+func (b Build) GetDataFromFrost6Classes() (map[int][]ObsRoadweather, error) {
 
 	// This is so long back we have image data
 	start := time.Date(2023, 2, 10, 0, 0, 0, 00, time.UTC)
-	stop := time.Now().UTC()
-	from := start
-	to := start.Add(24 * time.Hour)
-	count := 0
-	maxdays := stop.Sub(start).Hours() / 24
-	log.Printf("Samples from %.0f days: ", maxdays)
+	stop := b.stop()
 
-	classesCount := map[string]int{
-		"Dry":          0,
-		"Wet":          0,
-		"Snow":         0,
-		"Ice":          0,
-		"Wet+Snow":     0,
-		"Wet+Ice":      0,
-		"Snow+Ice":     0,
-		"Snow+Ice+Wet": 0,
+	obses, _, err := b.buildDataset(Scheme6, Sampler6, start, stop, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	precipitationAmounts := map[string]int{}
-	class2Obses := make(map[int][]ObsRoadweather)
+	return groupByClass(obses), nil
+}
 
-	for from.Before(stop) {
-		log.Printf("Getting obs batch %d of %0.0f .. ", count, maxdays)
-		count++
-		//2023-02-10T00:00:00Z
-		timespan := fmt.Sprintf("%s/%s", from.Format("2006-01-02T15:04Z"), to.Format("2006-01-02T15:04Z"))
-		//timespan := "2023-02-10T00:00Z/2023-02-11T00:00Z"
-		resp, err := obsRequest(sources, "road_ice_thickness,road_water_film_thickness,road_snow_thickness", timespan)
-		if err != nil {
-			log.Printf("GetDataFromFrost obsRequest: httpresp: %v error: %v", resp, err)
-			count--
-			continue
-		}
+// labelAppClasses are the names GetObsMapForLabelApp uses for ClassesMap8.
+var labelAppClasses = map[int]string{
+	DryE:              "Dry",
+	WetE:              "Water",
+	SnowE:             "Snow",
+	IceE:              "Ice",
+	WetAndSnowE:       "Water+Snow",
+	WetAndIceE:        "Water+Ice",
+	SnowAndIceE:       "Snow+Ice",
+	SnowAndIceAndWetE: "Snow+Ice+Water",
+}
 
-		times := resp.Data
+func GetObsMapForLabelApp() (map[string][]ObsRoadweather, error) {
+	return Build{}.GetObsMapForLabelApp()
+}
 
-		for t := 0; t < len(times); t++ {
-			roadConditionClass := -1
+func (b Build) GetObsMapForLabelApp() (map[string][]ObsRoadweather, error) {
 
-			//if times[t].ReferenceTime.Hour() == 0 || times[t].ReferenceTime.Hour() == 6 || times[t].ReferenceTime.Hour() == 12 || times[t].ReferenceTime.Hour() == 18 { // forEach 6th hour
+	// This is so long back we have image data
+	start := time.Date(2023, 2, 10, 0, 0, 0, 00, time.UTC)
+	//start := time.Date(2021, 2, 10, 0, 0, 0, 00, time.UTC)
+	// No ice after .. may maybe?
 
-			if times[t].ReferenceTime.UTC().Minute() != 0 {
-				continue
-			}
+	//stop := time.Date(2023, 5, 12, 0, 0, 0, 00, time.UTC)
+	stop := time.Date(2023, 11, 16, 0, 0, 0, 00, time.UTC)
+	//stop := time.Date(2023, 2, 12, 0, 0, 0, 00, time.UTC)
 
-			var iceThickness float32 = 0.0
-			var waterThickness float32 = 0.0
-			var snowThickness float32 = 0.0
-			for o := 0; o < len(times[t].Observations); o++ {
-				if times[t].Observations[o].Unit != "mm" { // Just in case ..
-					log.Printf("GetFrostObses() Unsupported unit: %s", times[t].Observations[o].Unit)
-					continue
-				}
-				if times[t].Observations[o].ElementID == "road_ice_thickness" {
+	class2Obses := make(map[string][]ObsRoadweather)
+	obses, _, err := b.buildDataset(Scheme8, SamplerLabelApp, start, stop, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, obs := range obses {
+		name := labelAppClasses[obs.Class]
+		class2Obses[name] = append(class2Obses[name], obs)
+	}
 
-					iceThickness = times[t].Observations[o].Value
-					k := fmt.Sprintf("Ice-%0.2f", iceThickness)
-					precipitationAmounts[k]++
+	return class2Obses, nil
+}
 
-				}
-				if times[t].Observations[o].ElementID == "road_water_film_thickness" {
-					waterThickness = times[t].Observations[o].Value
-					k := fmt.Sprintf("Water-%0.2f", waterThickness)
-					precipitationAmounts[k]++
-				}
-				if times[t].Observations[o].ElementID == "road_snow_thickness" {
-					snowThickness = times[t].Observations[o].Value
-					k := fmt.Sprintf("Snow-%0.2f", snowThickness)
-					precipitationAmounts[k]++
+func GetDataFromFrost8Classes() (map[int][]ObsRoadweather, error) {
+	return Build{}.GetDataFromFrost8Classes()
+}
 
-				}
-			}
+func (b Build) GetDataFromFrost8Classes() (map[int][]ObsRoadweather, error) {
 
-			if iceThickness == 0.0 && waterThickness == 0.0 && snowThickness == 0.0 && (times[t].ReferenceTime.UTC().Hour() == 0 || times[t].ReferenceTime.UTC().Hour() == 12 ||
-				times[t].ReferenceTime.UTC().Hour() == 6 || times[t].ReferenceTime.UTC().Hour() == 18) {
-				roadConditionClass = DryE
-				classesCount["Dry"]++
+	// This is so long back we have image data
+	start := time.Date(2023, 2, 10, 0, 0, 0, 00, time.UTC)
+	stop := b.stop()
 
-			} else if iceThickness == 0.0 && waterThickness == 0.0 && snowThickness == 0.0 {
-				// Downsample Dry
-				continue
-			} else if iceThickness > 0.0 && waterThickness > 0.0 && snowThickness > 0.0 {
-				roadConditionClass = SnowAndIceAndWetE
-				classesCount["Snow+Ice+Wet"]++
-			} else if iceThickness > 0.0 && snowThickness > 0.0 && waterThickness == 0.0 {
-				roadConditionClass = SnowAndIceE
-				classesCount["Snow+Ice"]++
-			} else if iceThickness > 0.0 && waterThickness > 0.0 && snowThickness == 0.0 {
-				roadConditionClass = WetAndIceE
-				classesCount["Wet+Ice"]++
-			} else if snowThickness > 0.0 && waterThickness > 0.0 && iceThickness == 0.0 {
-				roadConditionClass = WetAndSnowE
-				classesCount["Wet+Snow"]++
-			} else if iceThickness > 0.0 { // => Ice only
-				roadConditionClass = IceE
-				classesCount["Ice"]++
-			} else if snowThickness > 0.0 { // => Snow only
-				roadConditionClass = SnowE
-				classesCount["Snow"]++
-			} else if waterThickness > 0.0 { // => Wateronly
-				roadConditionClass = WetE
-				classesCount["Wet"]++
-			} else {
-				panic("Logic error")
-			}
-			obs := ObsRoadweather{}
-			obs.CamID = sourcesMap[times[t].SourceID].ID
-			obs.Station = times[t].SourceID
-			obs.IceThickness = iceThickness
-			obs.WaterFilmThickness = waterThickness
-			obs.SnowThickness = snowThickness
-			obs.RefTime = times[t].ReferenceTime.UTC()
-			obs.Class = roadConditionClass
-			class2Obses[roadConditionClass] = append(class2Obses[roadConditionClass], obs)
-		}
-		from = from.Add(24 * time.Hour)
-		to = to.Add(24 * time.Hour)
+	obses, amounts, err := b.buildDataset(Scheme8, Sampler8, start, stop, nil)
+	if err != nil {
+		return nil, err
 	}
+	printAmounts(amounts)
 
-	log.Printf("\nClasscount: %+v\n", classesCount)
-	log.Printf("precipitationAmounts: \n: %+v\n", precipitationAmounts)
+	return groupByClass(obses), nil
+}
 
-	return class2Obses, nil
+// Skip due to https://docs.google.com/spreadsheets/d/1xAF5QCVUiIHbwzYMpxVaqyiNqmhZ8ItdoNxlR6rMeqo/edit#gid=0
+// This is Frost IDs
+var skipList3 = map[string]bool{
+	"SN16620:0": true,
+	"SN30244:0": true, // No snow or Ice
+	"SN52390:0": true,
+	"SN67153:0": true,
 }
 
 // Dry  int = 0, Wet int = 1 // No snow an Ice, SnowAndOrIce int = 2
 func GetDataFromFrost3Classes() (map[int][]ObsRoadweather, error) {
+	return Build{}.GetDataFromFrost3Classes()
+}
 
-	sourcesMap, err := GetStationsWithSensor()
-	if err != nil {
-		log.Fatalf("GetStationsWithSensor: %v", err)
-	}
-
-	keys := maps.Keys(sourcesMap)
-	sources := strings.Join(keys, ",")
+func (b Build) GetDataFromFrost3Classes() (map[int][]ObsRoadweather, error) {
 
 	// This is so long back we have image data
 	start := time.Date(2023, 2, 10, 0, 0, 0, 00, time.UTC)
 	//start := time.Date(2021, 2, 10, 0, 0, 0, 00, time.UTC)
 	// No ice after .. may maybe?
-	stop := time.Now().UTC()
-	from := start
-	to := start.Add(24 * time.Hour)
-	count := 0
-	maxdays := stop.Sub(start).Hours() / 24
-	log.Printf("Samples from %.0f days: ", maxdays)
-
-	classesCount := map[string]int{
-		"Dry":                            0,
-		"Wet":                            0, // No snow or Ice
-		"SnowAndOrIceWithOrWithoutWater": 0,
-	}
-	class2Obses := make(map[int][]ObsRoadweather)
+	stop := b.stop()
 
-	for from.Before(stop) {
-		log.Printf("Getting obs batch %d of %0.0f .. ", count, maxdays)
-		count++
-		//2023-02-10T00:00:00Z
-		timespan := fmt.Sprintf("%s/%s", from.Format("2006-01-02T15:04Z"), to.Format("2006-01-02T15:04Z"))
-		//timespan := "2023-02-10T00:00Z/2023-02-11T00:00Z"
-		resp, err := obsRequest(sources, "road_ice_thickness,road_water_film_thickness,road_snow_thickness", timespan)
-		if err != nil {
-			log.Printf("GetDataFromFrost obsRequest: httpresp: %v error: %v", resp, err)
-			count--
-			continue
-		}
-
-		times := resp.Data
-
-		// Skip due to https://docs.google.com/spreadsheets/d/1xAF5QCVUiIHbwzYMpxVaqyiNqmhZ8ItdoNxlR6rMeqo/edit#gid=0
-		// This is Frost IDs
-		skipList := map[string]bool{
-			"SN16620:0": true,
-			"SN30244:0": true, // No snow or Ice
-			"SN52390:0": true,
-			"SN67153:0": true,
-		}
-
-		for t := 0; t < len(times); t++ {
-			_, exist := skipList[times[t].SourceID]
-			// If the key exists
-			if exist {
-				//fmt.Printf("%s in skiplist. Skipping\n", times[t].SourceID)
-				continue
-			}
-			roadConditionClass := -1
-
-			//if times[t].ReferenceTime.Hour() == 0 || times[t].ReferenceTime.Hour() == 6 || times[t].ReferenceTime.Hour() == 12 || times[t].ReferenceTime.Hour() == 18 { // forEach 6th hour
-
-			if times[t].ReferenceTime.UTC().Minute() != 0 {
-				continue
-			}
-
-			var iceThickness float32 = 0.0
-			var waterThickness float32 = 0.0
-			var snowThickness float32 = 0.0
-			for o := 0; o < len(times[t].Observations); o++ {
-				if times[t].Observations[o].Unit != "mm" { // Just in case ..
-					log.Printf("GetFrostObses() Unsupported unit: %s", times[t].Observations[o].Unit)
-					continue
-				}
-				if times[t].Observations[o].ElementID == "road_ice_thickness" {
-					iceThickness = times[t].Observations[o].Value
-
-				}
-				if times[t].Observations[o].ElementID == "road_water_film_thickness" {
-					waterThickness = times[t].Observations[o].Value
-				}
-				if times[t].Observations[o].ElementID == "road_snow_thickness" {
-					snowThickness = times[t].Observations[o].Value
-				}
-			}
-
-			if iceThickness == 0.0 && waterThickness == 0.0 && snowThickness == 0.0 {
-				roadConditionClass = Dry
-
-			} else if iceThickness > 0.0 || snowThickness > 0.0 {
-				roadConditionClass = SnowAndOrIce // Can also be be water or no-water
-
-			} else if waterThickness > 0.0 { // Can not be Snow an or Ice becasue ^
-				roadConditionClass = Wet
-
-			} else {
-				panic("Logic error")
-			}
-
-			if roadConditionClass == Dry && (times[t].ReferenceTime.UTC().Hour() == 0 || times[t].ReferenceTime.UTC().Hour() == 12 ||
-				times[t].ReferenceTime.UTC().Hour() == 6 || times[t].ReferenceTime.UTC().Hour() == 18) {
-				obs := ObsRoadweather{}
-				obs.CamID = sourcesMap[times[t].SourceID].ID
-				obs.Station = times[t].SourceID
-				obs.IceThickness = iceThickness
-				obs.WaterFilmThickness = waterThickness
-				obs.SnowThickness = snowThickness
-				obs.RefTime = times[t].ReferenceTime.UTC()
-				obs.Class = roadConditionClass
-				obs.FrostID = times[t].SourceID
-
-				class2Obses[roadConditionClass] = append(class2Obses[roadConditionClass], obs)
-				classesCount["Dry"]++
-				//fmt.Printf("Obs: %+v\n", obs)
-
-			} else if roadConditionClass == SnowAndOrIce && (times[t].ReferenceTime.UTC().Hour() == 0 || times[t].ReferenceTime.UTC().Hour() == 12 ||
-				times[t].ReferenceTime.UTC().Hour() == 6 || times[t].ReferenceTime.UTC().Hour() == 18) { // With or without water
-				obs := ObsRoadweather{}
-				obs.CamID = sourcesMap[times[t].SourceID].ID
-				obs.Station = times[t].SourceID
-				obs.IceThickness = iceThickness
-				obs.WaterFilmThickness = waterThickness
-				obs.SnowThickness = snowThickness
-				obs.RefTime = times[t].ReferenceTime.UTC()
-				obs.Class = roadConditionClass
-
-				class2Obses[roadConditionClass] = append(class2Obses[roadConditionClass], obs)
-				classesCount["SnowAndOrIceWithOrWithoutWater"]++
-				obs.FrostID = times[t].SourceID
-				//fmt.Printf("Obs: %+v\n", obs)
-
-			} else if roadConditionClass == Wet && (times[t].ReferenceTime.UTC().Hour() == 0 || times[t].ReferenceTime.UTC().Hour() == 12 ||
-				times[t].ReferenceTime.UTC().Hour() == 6 || times[t].ReferenceTime.UTC().Hour() == 18) {
-
-				obs := ObsRoadweather{}
-				obs.CamID = sourcesMap[times[t].SourceID].ID
-				obs.Station = times[t].SourceID
-				obs.IceThickness = iceThickness
-				obs.WaterFilmThickness = waterThickness
-				obs.SnowThickness = snowThickness
-				obs.RefTime = times[t].ReferenceTime.UTC()
-				obs.Class = roadConditionClass
-				obs.FrostID = times[t].SourceID
-				//fmt.Printf("Obs: %+v\n", obs)
-
-				class2Obses[roadConditionClass] = append(class2Obses[roadConditionClass], obs)
-
-				classesCount["Wet"]++
-			}
-		}
-		from = from.Add(24 * time.Hour)
-		to = to.Add(24 * time.Hour)
+	obses, _, err := b.buildDataset(Scheme3, Sampler3, start, stop, skipList3)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("\nCLASSCOUNT: %+v", classesCount)
-
-	return class2Obses, nil
+	return groupByClass(obses), nil
 }
 
 func main() {
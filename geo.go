@@ -0,0 +1,168 @@
+package frostclient
+
+import (
+	"math"
+	"strings"
+
+	"github.com/metno/roadlabels/pkg/db"
+)
+
+const earthRadiusKm = 6371.0
+
+// CameraLocation is a camera with its position. Masl is only used when
+// GeoOptions.MaxAltitudeDiff is set.
+type CameraLocation struct {
+	Camera    db.Camera
+	Latitude  float64
+	Longitude float64
+	Masl      float64
+}
+
+// CameraLocator gives the position of a camera, false if it is not known.
+type CameraLocator func(cam db.Camera) (CameraLocation, bool)
+
+// CameraLocations locates the cameras in locs by ID, e.g. from a list of
+// camera coordinates.
+func CameraLocations(locs []CameraLocation) CameraLocator {
+	byID := make(map[int]CameraLocation, len(locs))
+	for _, loc := range locs {
+		byID[loc.Camera.ID] = loc
+	}
+	return func(cam db.Camera) (CameraLocation, bool) {
+		loc, ok := byID[cam.ID]
+		loc.Camera = cam
+		return loc, ok
+	}
+}
+
+type GeoOptions struct {
+	MaxDistance     float64 // km
+	MaxAltitudeDiff float64 // m, 0 => not checked
+}
+
+var DefaultGeoOptions = GeoOptions{
+	MaxDistance: 1.0,
+}
+
+type GeoMatch struct {
+	Camera       db.Camera
+	StationID    string
+	Distance     float64 // km
+	AltitudeDiff float64 // m, station minus camera
+}
+
+// Haversine returns the great-circle distance in km between two points given
+// in degrees.
+func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// RoadweatherCandidates returns the stations with ice, water film and snow
+// sensors, the only ones worth matching a camera to by location.
+func RoadweatherCandidates(stations []Source) ([]Source, error) {
+	series, err := obsTypeReq("")
+	if err != nil {
+		return nil, err
+	}
+	elements := make(map[string]map[string]bool)
+	for _, ts := range series.Data {
+		id := strings.Split(ts.SourceID, ":")[0]
+		if elements[id] == nil {
+			elements[id] = make(map[string]bool)
+		}
+		elements[id][ts.ElementID] = true
+	}
+
+	candidates := []Source{}
+	for _, st := range stations {
+		complete := true
+		for _, e := range RoadweatherElements {
+			complete = complete && elements[st.ID][e]
+		}
+		if complete {
+			candidates = append(candidates, st)
+		}
+	}
+	return candidates, nil
+}
+
+// NearestStation returns the station in stations closest to cam that is within
+// opts.MaxDistance and opts.MaxAltitudeDiff. Frost coordinates are lon, lat.
+// Filter stations with RoadweatherCandidates first, or a nearby station
+// without sensors wins the match.
+func NearestStation(cam CameraLocation, stations []Source, opts GeoOptions) (GeoMatch, bool) {
+	best := GeoMatch{Camera: cam.Camera}
+	found := false
+	for s := 0; s < len(stations); s++ {
+		coords := stations[s].Geometry.Coordinates
+		if len(coords) < 2 {
+			continue
+		}
+		dist := Haversine(cam.Latitude, cam.Longitude, coords[1], coords[0])
+		if dist > opts.MaxDistance {
+			continue
+		}
+		altDiff := float64(stations[s].Masl) - cam.Masl
+		if opts.MaxAltitudeDiff > 0 && math.Abs(altDiff) > opts.MaxAltitudeDiff {
+			continue
+		}
+		if !found || dist < best.Distance {
+			best.StationID = stations[s].ID
+			best.Distance = dist
+			best.AltitudeDiff = altDiff
+			found = true
+		}
+	}
+	return best, found
+}
+
+// MatchByLocation returns the nearest qualifying station for every camera in
+// cams that has one.
+func MatchByLocation(cams []CameraLocation, stations []Source, opts GeoOptions) []GeoMatch {
+	matches := []GeoMatch{}
+	for c := 0; c < len(cams); c++ {
+		m, ok := NearestStation(cams[c], stations, opts)
+		if ok {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// AddGeoMatches adds cameras matched by location to the report, typically the
+// ones left in UnmatchedCameras after matching by ID.
+func (r *MatchReport) AddGeoMatches(geo []GeoMatch) {
+	for _, g := range geo {
+		r.Geo = append(r.Geo, g)
+
+		added := false
+		for m := range r.Matches {
+			if r.Matches[m].StationID == g.StationID {
+				r.Matches[m].Cameras = append(r.Matches[m].Cameras, g.Camera)
+				added = true
+				break
+			}
+		}
+		if !added {
+			r.Matches = append(r.Matches, StationMatch{StationID: g.StationID, Cameras: []db.Camera{g.Camera}})
+			for s, id := range r.UnmatchedStations {
+				if id == g.StationID {
+					r.UnmatchedStations = append(r.UnmatchedStations[:s], r.UnmatchedStations[s+1:]...)
+					break
+				}
+			}
+		}
+
+		for c, cam := range r.UnmatchedCameras {
+			if cam.ID == g.Camera.ID {
+				r.UnmatchedCameras = append(r.UnmatchedCameras[:c], r.UnmatchedCameras[c+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package frostclient
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/metno/roadlabels/pkg/db"
+)
+
+func TestHaversine(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want, tolerance        float64
+	}{
+		{"same point", 60, 10, 60, 10, 0, 1e-9},
+		{"one degree of latitude", 60, 10, 61, 10, 111.19, 0.01},
+		{"one degree of longitude on the equator", 0, 10, 0, 11, 111.19, 0.01},
+		{"Oslo to Bergen", 59.9139, 10.7522, 60.3913, 5.3221, 305, 2},
+		{"symmetric", 60.3913, 5.3221, 59.9139, 10.7522, 305, 2},
+	}
+	for _, tt := range tests {
+		got := Haversine(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+		if math.Abs(got-tt.want) > tt.tolerance {
+			t.Errorf("%s: Haversine = %.3f km, want %.3f ± %g", tt.name, got, tt.want, tt.tolerance)
+		}
+	}
+}
+
+// source is a station at lat, lon, Frost giving coordinates as lon, lat.
+func source(id string, lat, lon float64, masl int) Source {
+	return Source{ID: id, Geometry: Geometry{Coordinates: []float64{lon, lat}}, Masl: masl}
+}
+
+func TestNearestStation(t *testing.T) {
+	stations := []Source{
+		source("SN1", 60.000, 10.000, 100),
+		source("SN2", 60.005, 10.000, 400), // about 0.56 km north
+		source("SN3", 60.100, 10.000, 100), // about 11 km north
+		{ID: "SN4"},                        // no coordinates
+	}
+	cam := func(lat, lon, masl float64) CameraLocation {
+		return CameraLocation{Camera: db.Camera{ID: 1}, Latitude: lat, Longitude: lon, Masl: masl}
+	}
+
+	tests := []struct {
+		name    string
+		cam     CameraLocation
+		opts    GeoOptions
+		want    string
+		matched bool
+	}{
+		{"at a station", cam(60, 10, 100), DefaultGeoOptions, "SN1", true},
+		{"closer to the second", cam(60.004, 10, 100), DefaultGeoOptions, "SN2", true},
+		{"too far from all", cam(60.05, 10, 100), DefaultGeoOptions, "", false},
+		{"within a larger distance", cam(60.09, 10, 100), GeoOptions{MaxDistance: 2}, "SN3", true},
+		{"altitude rules out the nearest", cam(60.004, 10, 100), GeoOptions{MaxDistance: 1, MaxAltitudeDiff: 50}, "SN1", true},
+		{"altitude rules out all", cam(60.004, 10, 250), GeoOptions{MaxDistance: 1, MaxAltitudeDiff: 50}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := NearestStation(tt.cam, stations, tt.opts)
+		if ok != tt.matched || got.StationID != tt.want {
+			t.Errorf("%s: NearestStation = %q, %v, want %q, %v", tt.name, got.StationID, ok, tt.want, tt.matched)
+		}
+	}
+
+	m, _ := NearestStation(cam(60.004, 10, 100), stations, DefaultGeoOptions)
+	if m.AltitudeDiff != 300 || math.Abs(m.Distance-0.111) > 0.001 {
+		t.Errorf("match = %+v, want 300 m higher and 0.111 km away", m)
+	}
+}
+
+func TestAddGeoMatches(t *testing.T) {
+	stations := []Source{
+		{ID: "SN1", ExternalIds: []string{"100101"}},
+		source("SN2", 60, 10, 0),
+		source("SN3", 61, 10, 0),
+	}
+	stations[0].Geometry.Coordinates = []float64{11, 60}
+	cams := []db.Camera{{ID: 1, ForeignID: "100101_1"}, {ID: 2}, {ID: 3}, {ID: 4}}
+
+	report := MatchStations(stations, cams)
+	locate := CameraLocations([]CameraLocation{
+		{Camera: db.Camera{ID: 2}, Latitude: 60, Longitude: 10.001},
+		{Camera: db.Camera{ID: 3}, Latitude: 60, Longitude: 11.001},
+		{Camera: db.Camera{ID: 4}, Latitude: 65, Longitude: 10},
+	})
+	locations := []CameraLocation{}
+	for _, cam := range report.UnmatchedCameras {
+		if loc, ok := locate(cam); ok {
+			locations = append(locations, loc)
+		}
+	}
+	report.AddGeoMatches(MatchByLocation(locations, stations, DefaultGeoOptions))
+
+	got := make(map[string][]int)
+	for id, cams := range report.StationCameras() {
+		for _, c := range cams {
+			got[id] = append(got[id], c.ID)
+		}
+	}
+	want := map[string][]int{"SN1": {1, 3}, "SN2": {2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("matches = %v, want %v", got, want)
+	}
+	if len(report.Geo) != 2 {
+		t.Errorf("geo matches = %+v, want 2", report.Geo)
+	}
+	if len(report.UnmatchedCameras) != 1 || report.UnmatchedCameras[0].ID != 4 {
+		t.Errorf("unmatched cameras = %+v, want camera 4", report.UnmatchedCameras)
+	}
+	if !reflect.DeepEqual(report.UnmatchedStations, []string{"SN3"}) {
+		t.Errorf("unmatched stations = %v, want SN3", report.UnmatchedStations)
+	}
+}
+
+func TestCameraLocations(t *testing.T) {
+	locate := CameraLocations([]CameraLocation{{Camera: db.Camera{ID: 2}, Latitude: 60, Longitude: 10}})
+	cam := db.Camera{ID: 2, ForeignID: "200202_1"}
+	loc, ok := locate(cam)
+	if !ok || loc.Latitude != 60 || loc.Camera.ForeignID != "200202_1" {
+		t.Errorf("locate(2) = %+v, %v, want 60 N with the camera from the DB", loc, ok)
+	}
+	if _, ok := locate(db.Camera{ID: 3}); ok {
+		t.Errorf("locate(3) found an unknown camera")
+	}
+}
@@ -0,0 +1,148 @@
+package frostclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/metno/roadlabels/pkg/db"
+)
+
+// FeatureCollection is a GeoJSON (RFC 7946) feature collection.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string         `json:"type"`
+	Geometry   *PointGeometry `json:"geometry"` // null when the source has no coordinates
+	Properties map[string]any `json:"properties"`
+}
+
+type PointGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"` // lon, lat as in Frost
+}
+
+// AvailableElements returns the elements with timeseries at each station.
+func AvailableElements(stationIDs []string) (map[string][]string, error) {
+	elements := make(map[string][]string)
+	for _, id := range stationIDs {
+		obstypes, err := obsTypeReq(id)
+		if err != nil {
+			return elements, fmt.Errorf("obsTypeReq(%s): %v", id, err)
+		}
+		seen := make(map[string]bool)
+		for _, ts := range obstypes.Data {
+			if !seen[ts.ElementID] {
+				seen[ts.ElementID] = true
+				elements[id] = append(elements[id], ts.ElementID)
+			}
+		}
+		sort.Strings(elements[id])
+	}
+	return elements, nil
+}
+
+// StationFeatures makes a feature of each station with the cameras it was
+// matched to in cams, as from GetStationCameras, its elements, if elements is
+// not nil, and its latest observation in obses classified by scheme. Source
+// IDs like "SN12345:0" are looked up by station, "SN12345".
+func StationFeatures(stations []Source, cams map[string][]db.Camera, elements map[string][]string, obses []ObsRoadweather, scheme Scheme) FeatureCollection {
+	stationOf := func(id string) string { return strings.Split(id, ":")[0] }
+
+	camIDs := make(map[string][]int)
+	for id, cs := range cams {
+		for _, c := range cs {
+			camIDs[stationOf(id)] = append(camIDs[stationOf(id)], c.ID)
+		}
+	}
+	elms := make(map[string][]string)
+	for id, es := range elements {
+		elms[stationOf(id)] = append(elms[stationOf(id)], es...)
+	}
+	latest := make(map[string]ObsRoadweather)
+	for _, obs := range obses {
+		s := stationOf(obs.Station)
+		if l, ok := latest[s]; !ok || obs.RefTime.After(l.RefTime) {
+			latest[s] = obs
+		}
+	}
+
+	fc := FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+	for _, st := range stations {
+		ids := uniqueInts(camIDs[st.ID])
+		props := map[string]any{
+			"stationId": st.ID,
+			"name":      st.Name,
+			"matched":   len(ids) > 0,
+			"cameraIds": ids,
+		}
+		if elements != nil {
+			props["elements"] = uniqueStrings(elms[st.ID])
+		}
+		if obs, ok := latest[st.ID]; ok {
+			props["latestTime"] = obs.RefTime.UTC().Format(time.RFC3339)
+			props["latestClass"] = obs.Class
+			props["latestClassName"] = scheme.Classes[obs.Class]
+			props["scheme"] = scheme.Name
+		}
+
+		f := Feature{Type: "Feature", Properties: props}
+		if len(st.Geometry.Coordinates) >= 2 {
+			f.Geometry = &PointGeometry{Type: "Point", Coordinates: st.Geometry.Coordinates[:2]}
+		}
+		fc.Features = append(fc.Features, f)
+	}
+	return fc
+}
+
+func WriteGeoJSON(w io.Writer, fc FeatureCollection) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fc)
+}
+
+// WriteGeoJSONFile writes fc to the file path.
+func WriteGeoJSONFile(path string, fc FeatureCollection) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("os.Create(%s): %v", path, err)
+	}
+	if err := WriteGeoJSON(f, fc); err != nil {
+		f.Close()
+		return fmt.Errorf("WriteGeoJSON(%s): %v", path, err)
+	}
+	return f.Close()
+}
+
+func uniqueInts(vs []int) []int {
+	unique := []int{}
+	seen := make(map[int]bool)
+	for _, v := range vs {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Ints(unique)
+	return unique
+}
+
+func uniqueStrings(vs []string) []string {
+	unique := []string{}
+	seen := make(map[string]bool)
+	for _, v := range vs {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,93 @@
+package frostclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/metno/roadlabels/pkg/db"
+)
+
+func TestStationFeatures(t *testing.T) {
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	stations := []Source{
+		{ID: "SN1", Name: "Matched", Geometry: Geometry{Coordinates: []float64{10, 60, 100}}},
+		{ID: "SN2", Name: "Unmatched", Geometry: Geometry{Coordinates: []float64{11, 61}}},
+		{ID: "SN3", Name: "No coordinates"},
+	}
+	cams := map[string][]db.Camera{
+		"SN1:0": {{ID: 7}, {ID: 3}},
+		"SN1:1": {{ID: 3}},
+	}
+	elements := map[string][]string{
+		"SN1:0": {"road_ice_thickness", "road_snow_thickness"},
+		"SN1:1": {"road_ice_thickness"},
+	}
+	obses := []ObsRoadweather{
+		{RefTime: at, Station: "SN1:0", Class: DryE},
+		{RefTime: at.Add(time.Hour), Station: "SN1:1", Class: IceE},
+		{RefTime: at.Add(-time.Hour), Station: "SN1:0", Class: WetE},
+	}
+	fc := StationFeatures(stations, cams, elements, obses, Scheme8)
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 3 {
+		t.Fatalf("collection %+v", fc)
+	}
+	tests := []struct {
+		name     string
+		geometry *PointGeometry
+		props    map[string]any
+	}{
+		{"matched", &PointGeometry{"Point", []float64{10, 60}}, map[string]any{
+			"stationId": "SN1", "name": "Matched", "matched": true, "cameraIds": []int{3, 7},
+			"elements":   []string{"road_ice_thickness", "road_snow_thickness"},
+			"latestTime": "2023-02-10T13:00:00Z", "latestClass": IceE, "latestClassName": "Ice", "scheme": "8classes",
+		}},
+		{"unmatched", &PointGeometry{"Point", []float64{11, 61}}, map[string]any{
+			"stationId": "SN2", "name": "Unmatched", "matched": false, "cameraIds": []int{}, "elements": []string{},
+		}},
+		{"no coordinates", nil, map[string]any{
+			"stationId": "SN3", "name": "No coordinates", "matched": false, "cameraIds": []int{}, "elements": []string{},
+		}},
+	}
+	for i, tt := range tests {
+		f := fc.Features[i]
+		if !reflect.DeepEqual(f.Geometry, tt.geometry) {
+			t.Errorf("%s: geometry %+v, want %+v", tt.name, f.Geometry, tt.geometry)
+		}
+		if !reflect.DeepEqual(f.Properties, tt.props) {
+			t.Errorf("%s: properties\n got %v\nwant %v", tt.name, f.Properties, tt.props)
+		}
+	}
+
+	if _, ok := StationFeatures(stations, cams, nil, nil, Scheme8).Features[0].Properties["elements"]; ok {
+		t.Errorf("elements without AvailableElements")
+	}
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	fc := StationFeatures([]Source{{ID: "SN1", Geometry: Geometry{Coordinates: []float64{10.5, 60.25}}}, {ID: "SN2"}}, nil, nil, nil, Scheme8)
+	var b bytes.Buffer
+	if err := WriteGeoJSON(&b, fc); err != nil {
+		t.Fatalf("WriteGeoJSON: %v", err)
+	}
+	var decoded FeatureCollection
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("decoding: %v\n%s", err, b.String())
+	}
+	want := FeatureCollection{Type: "FeatureCollection", Features: []Feature{
+		{Type: "Feature", Geometry: &PointGeometry{"Point", []float64{10.5, 60.25}}, Properties: map[string]any{
+			"stationId": "SN1", "name": "", "matched": false, "cameraIds": []any{},
+		}},
+		{Type: "Feature", Properties: map[string]any{
+			"stationId": "SN2", "name": "", "matched": false, "cameraIds": []any{},
+		}},
+	}}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("decoded\n%+v\nwant\n%+v", decoded, want)
+	}
+	if !bytes.Contains(b.Bytes(), []byte(`"geometry": null`)) {
+		t.Errorf("no null geometry in\n%s", b.String())
+	}
+}
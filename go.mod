@@ -3,8 +3,7 @@ module github.com/metno/frostclient-roadweather
 go 1.19
 
 require (
+	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/metno/roadlabels v0.0.3
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9
 )
-
-require github.com/mattn/go-sqlite3 v1.14.17 // indirect
@@ -0,0 +1,289 @@
+package frostclient
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Image is a camera image captured at Time.
+type Image struct {
+	CamID int
+	Time  time.Time
+	Path  string
+}
+
+// ImageLabel pairs an image with the observation labelling it. Offset is the
+// image time minus the time of the nearest observation. An interpolated Obs
+// has the time of the image.
+type ImageLabel struct {
+	Image        Image
+	Obs          ObsRoadweather
+	Offset       time.Duration
+	Interpolated bool
+}
+
+// ImageMatchOptions control MatchImages.
+type ImageMatchOptions struct {
+	// Tolerance is the largest offset to the nearest observation
+	Tolerance time.Duration
+	// Interpolate labels images with no observation within Tolerance when
+	// the observations before and after, at most MaxInterpolation apart,
+	// have the same class. The thicknesses are interpolated linearly.
+	Interpolate      bool
+	MaxInterpolation time.Duration
+}
+
+var DefaultImageMatchOptions = ImageMatchOptions{
+	Tolerance:        5 * time.Minute,
+	Interpolate:      true,
+	MaxInterpolation: time.Hour,
+}
+
+// ImageMatchReport counts the outcome of MatchImages.
+type ImageMatchReport struct {
+	Matched      int
+	Interpolated int
+	Rejected     int
+}
+
+func (r ImageMatchReport) String() string {
+	return fmt.Sprintf("Images: matched %d, interpolated %d, rejected %d", r.Matched, r.Interpolated, r.Rejected)
+}
+
+// MatchImages labels each image with the observation of its camera nearest
+// in time, see ImageMatchOptions. Images that can not be labelled are left
+// out and counted as rejected.
+func MatchImages(images []Image, obses []ObsRoadweather, opts ImageMatchOptions) ([]ImageLabel, ImageMatchReport) {
+	byCam := obsByCam(obses)
+	labels := []ImageLabel{}
+	report := ImageMatchReport{}
+	for _, img := range images {
+		label, ok := matchImage(byCam[img.CamID], img, opts)
+		switch {
+		case !ok:
+			report.Rejected++
+			continue
+		case label.Interpolated:
+			report.Interpolated++
+		default:
+			report.Matched++
+		}
+		labels = append(labels, label)
+	}
+	return labels, report
+}
+
+// obsByCam returns the observations of each camera sorted by time.
+func obsByCam(obses []ObsRoadweather) map[int][]ObsRoadweather {
+	byCam := make(map[int][]ObsRoadweather)
+	for _, obs := range obses {
+		byCam[obs.CamID] = append(byCam[obs.CamID], obs)
+	}
+	for _, series := range byCam {
+		sort.SliceStable(series, func(i, j int) bool { return series[i].RefTime.Before(series[j].RefTime) })
+	}
+	return byCam
+}
+
+// matchImage labels img from series, the observations of its camera.
+func matchImage(series []ObsRoadweather, img Image, opts ImageMatchOptions) (ImageLabel, bool) {
+	i := sort.Search(len(series), func(i int) bool { return !series[i].RefTime.Before(img.Time) })
+
+	// series[i-1] is the last observation before the image, series[i] the first at or after
+	var before, after *ObsRoadweather
+	if i > 0 {
+		before = &series[i-1]
+	}
+	if i < len(series) {
+		after = &series[i]
+	}
+
+	nearest := after
+	if before != nil && (after == nil || img.Time.Sub(before.RefTime) < after.RefTime.Sub(img.Time)) {
+		nearest = before
+	}
+	if nearest != nil && absDuration(img.Time.Sub(nearest.RefTime)) <= opts.Tolerance {
+		return ImageLabel{Image: img, Obs: *nearest, Offset: img.Time.Sub(nearest.RefTime)}, true
+	}
+
+	if opts.Interpolate && before != nil && after != nil && before.Class == after.Class &&
+		after.RefTime.Sub(before.RefTime) <= opts.MaxInterpolation {
+		return ImageLabel{Image: img, Obs: interpolate(*before, *after, img.Time),
+			Offset: img.Time.Sub(nearest.RefTime), Interpolated: true}, true
+	}
+	return ImageLabel{}, false
+}
+
+// interpolate returns the observation at t between a and b, of their class.
+func interpolate(a, b ObsRoadweather, t time.Time) ObsRoadweather {
+	f := float32(t.Sub(a.RefTime)) / float32(b.RefTime.Sub(a.RefTime))
+	lerp := func(x, y float32) float32 { return x + (y-x)*f }
+
+	obs := a
+	obs.RefTime = t
+	obs.IceThickness = lerp(a.IceThickness, b.IceThickness)
+	obs.WaterFilmThickness = lerp(a.WaterFilmThickness, b.WaterFilmThickness)
+	obs.SnowThickness = lerp(a.SnowThickness, b.SnowThickness)
+	obs.Present = a.Present & b.Present
+	return obs
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// ImagesFromDir lists the images of camera camID in dir, taking the time
+// from the file name parsed with layout in UTC, like
+// "20060102T1504Z.jpg". Files not matching layout are skipped.
+func ImagesFromDir(dir string, camID int, layout string) ([]Image, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadDir(%s): %v", dir, err)
+	}
+	images := []Image{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		t, err := time.ParseInLocation(layout, e.Name(), time.UTC)
+		if err != nil {
+			continue
+		}
+		images = append(images, Image{CamID: camID, Time: t.UTC(), Path: filepath.Join(dir, e.Name())})
+	}
+	return images, nil
+}
+
+// ImagesFromCameraDirs lists the images in the subdirectories of root named
+// by camera ID, see ImagesFromDir.
+func ImagesFromCameraDirs(root string, layout string) ([]Image, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadDir(%s): %v", root, err)
+	}
+	images := []Image{}
+	for _, e := range entries {
+		camID, err := strconv.Atoi(e.Name())
+		if !e.IsDir() || err != nil {
+			continue
+		}
+		imgs, err := ImagesFromDir(filepath.Join(root, e.Name()), camID, layout)
+		if err != nil {
+			return images, err
+		}
+		images = append(images, imgs...)
+	}
+	return images, nil
+}
+
+// ImageTable names the table of camera images in the roadlabels database
+// and its columns. The time may be Unix seconds or RFC 3339 text.
+type ImageTable struct {
+	Table string
+	CamID string
+	Time  string
+	Path  string // may be NULL
+}
+
+// RoadlabelsImages is the image table read by ImagesFromDB.
+var RoadlabelsImages = ImageTable{Table: "images", CamID: "cam_id", Time: "ts", Path: "path"}
+
+// CameraFilter selects the rows of cameras CamIDs, all when empty, from From
+// up to To, open ended when zero.
+type CameraFilter struct {
+	CamIDs   []int
+	From, To time.Time
+}
+
+// includes reports whether t is in the period of f.
+func (f CameraFilter) includes(t time.Time) bool {
+	return (f.From.IsZero() || !t.Before(f.From)) && (f.To.IsZero() || t.Before(f.To))
+}
+
+// queryCameras selects columns from table for the cameras of f, in camCol.
+// The period is checked by the caller after parsing the time.
+func queryCameras(conn *sql.DB, table, camCol string, columns []string, f CameraFilter) (*sql.Rows, error) {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), quoteIdent(table))
+	args := []any{}
+	if len(f.CamIDs) > 0 {
+		marks := make([]string, len(f.CamIDs))
+		for i, id := range f.CamIDs {
+			marks[i] = "?"
+			args = append(args, id)
+		}
+		query += fmt.Sprintf(" WHERE %s IN (%s)", quoteIdent(camCol), strings.Join(marks, ", "))
+	}
+	return conn.Query(query, args...)
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// ImagesFromDB lists the images selected by f from RoadlabelsImages, sorted
+// by camera and time.
+func ImagesFromDB(conn *sql.DB, f CameraFilter) ([]Image, error) {
+	t := RoadlabelsImages
+	rows, err := queryCameras(conn, t.Table, t.CamID, []string{t.CamID, t.Time, t.Path}, f)
+	if err != nil {
+		return nil, fmt.Errorf("query images: %v", err)
+	}
+	defer rows.Close()
+
+	images := []Image{}
+	for rows.Next() {
+		var img Image
+		var ts any
+		var path sql.NullString
+		if err := rows.Scan(&img.CamID, &ts, &path); err != nil {
+			return images, fmt.Errorf("scan image: %v", err)
+		}
+		img.Time, err = scanTime(ts)
+		if err != nil {
+			return images, fmt.Errorf("image time %v of camera %d: %v", ts, img.CamID, err)
+		}
+		if !f.includes(img.Time) {
+			continue
+		}
+		img.Path = path.String
+		images = append(images, img)
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		if images[i].CamID != images[j].CamID {
+			return images[i].CamID < images[j].CamID
+		}
+		return images[i].Time.Before(images[j].Time)
+	})
+	return images, rows.Err()
+}
+
+// scanTime converts a time scanned from a database, Unix seconds or RFC 3339
+// text, to UTC.
+func scanTime(ts any) (time.Time, error) {
+	switch v := ts.(type) {
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case time.Time:
+		return v.UTC(), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		return t.UTC(), err
+	case []byte:
+		t, err := time.Parse(time.RFC3339, string(v))
+		return t.UTC(), err
+	}
+	return time.Time{}, fmt.Errorf("unsupported type %T", ts)
+}
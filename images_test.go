@@ -0,0 +1,153 @@
+package frostclient
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatchImages(t *testing.T) {
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	min := func(m int) time.Time { return at.Add(time.Duration(m) * time.Minute) }
+	obses := []ObsRoadweather{
+		{RefTime: min(40), CamID: 1, IceThickness: 1, Class: IceE, Present: AllPresent},
+		{RefTime: min(0), CamID: 1, IceThickness: 0.5, Class: IceE, Present: IcePresent},
+		{RefTime: min(100), CamID: 1, Class: DryE, Present: AllPresent},
+		{RefTime: min(0), CamID: 2, Class: DryE, Present: AllPresent},
+	}
+	tests := []struct {
+		name         string
+		img          Image
+		offset       time.Duration
+		interpolated bool
+		want         ObsRoadweather // zero when rejected
+	}{
+		{"exact", Image{CamID: 1, Time: min(0)}, 0, false, obses[1]},
+		{"nearest after", Image{CamID: 1, Time: min(37)}, -3 * time.Minute, false, obses[0]},
+		{"nearest before", Image{CamID: 1, Time: min(103)}, 3 * time.Minute, false, obses[2]},
+		{"other camera", Image{CamID: 2, Time: min(4)}, 4 * time.Minute, false, obses[3]},
+		{"interpolated", Image{CamID: 1, Time: min(10)}, 10 * time.Minute, true, ObsRoadweather{
+			RefTime: min(10), CamID: 1, IceThickness: 0.625, Class: IceE, Present: IcePresent}},
+		{"classes differ", Image{CamID: 1, Time: min(70)}, 0, false, ObsRoadweather{}},
+		{"after the last", Image{CamID: 1, Time: min(110)}, 0, false, ObsRoadweather{}},
+		{"no observations", Image{CamID: 3, Time: min(0)}, 0, false, ObsRoadweather{}},
+	}
+	for _, tt := range tests {
+		labels, report := MatchImages([]Image{tt.img}, obses, DefaultImageMatchOptions)
+		if tt.want == (ObsRoadweather{}) {
+			if len(labels) != 0 || report.Rejected != 1 {
+				t.Errorf("%s: %+v, %v, want rejected", tt.name, labels, report)
+			}
+			continue
+		}
+		want := ImageLabel{Image: tt.img, Obs: tt.want, Offset: tt.offset, Interpolated: tt.interpolated}
+		if len(labels) != 1 || !reflect.DeepEqual(labels[0], want) {
+			t.Errorf("%s: %+v\nwant %+v", tt.name, labels, want)
+		}
+	}
+
+	// Interpolation spans at most MaxInterpolation and can be turned off
+	opts := DefaultImageMatchOptions
+	opts.MaxInterpolation = 30 * time.Minute
+	if labels, _ := MatchImages([]Image{{CamID: 1, Time: min(10)}}, obses, opts); len(labels) != 0 {
+		t.Errorf("interpolated over 40 minutes: %+v", labels)
+	}
+	opts = DefaultImageMatchOptions
+	opts.Interpolate = false
+	if labels, _ := MatchImages([]Image{{CamID: 1, Time: min(10)}}, obses, opts); len(labels) != 0 {
+		t.Errorf("interpolated when off: %+v", labels)
+	}
+
+	images := []Image{{CamID: 1, Time: min(0)}, {CamID: 1, Time: min(20)}, {CamID: 1, Time: min(70)}}
+	if _, report := MatchImages(images, obses, DefaultImageMatchOptions); report != (ImageMatchReport{1, 1, 1}) {
+		t.Errorf("report %v, want 1 matched, 1 interpolated, 1 rejected", report)
+	}
+}
+
+func TestImagesFromDir(t *testing.T) {
+	root := t.TempDir()
+	layout := "20060102T1504Z.jpg"
+	for _, name := range []string{"7/20230210T1200Z.jpg", "7/20230210T1210Z.jpg", "7/notes.txt", "12/20230210T1300Z.jpg", "cam/20230210T1200Z.jpg"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	images, err := ImagesFromCameraDirs(root, layout)
+	if err != nil {
+		t.Fatalf("ImagesFromCameraDirs: %v", err)
+	}
+	got := []string{}
+	for _, img := range images {
+		rel, _ := filepath.Rel(root, img.Path)
+		got = append(got, fmt.Sprintf("%d %s %s", img.CamID, img.Time.Format(time.RFC3339), rel))
+	}
+	want := []string{
+		"12 2023-02-10T13:00:00Z " + filepath.Join("12", "20230210T1300Z.jpg"),
+		"7 2023-02-10T12:00:00Z " + filepath.Join("7", "20230210T1200Z.jpg"),
+		"7 2023-02-10T12:10:00Z " + filepath.Join("7", "20230210T1210Z.jpg"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("images %v, want %v", got, want)
+	}
+	if _, err := ImagesFromDir(filepath.Join(root, "missing"), 1, layout); err == nil {
+		t.Errorf("ImagesFromDir of a missing directory succeeded")
+	}
+}
+
+func TestImagesFromDB(t *testing.T) {
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "roadlabels.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE images (cam_id INTEGER, ts, path TEXT);
+		INSERT INTO images VALUES
+			(2, 1676030400, '/img/2/a.jpg'),
+			(1, '2023-02-10T13:00:00+01:00', NULL),
+			(1, 1676034000, '/img/1/b.jpg'),
+			(3, 1676030400, '/img/3/a.jpg')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter CameraFilter
+		want   []Image
+	}{
+		{"all", CameraFilter{}, []Image{
+			{CamID: 1, Time: at},
+			{CamID: 1, Time: at.Add(time.Hour), Path: "/img/1/b.jpg"},
+			{CamID: 2, Time: at, Path: "/img/2/a.jpg"},
+			{CamID: 3, Time: at, Path: "/img/3/a.jpg"},
+		}},
+		{"cameras", CameraFilter{CamIDs: []int{1, 3}, To: at.Add(time.Hour)}, []Image{
+			{CamID: 1, Time: at},
+			{CamID: 3, Time: at, Path: "/img/3/a.jpg"},
+		}},
+		{"from", CameraFilter{From: at.Add(time.Minute)}, []Image{
+			{CamID: 1, Time: at.Add(time.Hour), Path: "/img/1/b.jpg"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := ImagesFromDB(conn, tt.filter)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %+v, %v\nwant %+v", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := conn.Exec(`INSERT INTO images VALUES (4, 'yesterday', NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImagesFromDB(conn, CameraFilter{CamIDs: []int{4}}); err == nil {
+		t.Errorf("unparsable time: no error")
+	}
+}
@@ -0,0 +1,121 @@
+package frostclient
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The kinds of records in a JSON Lines file.
+const (
+	KindObsRoadweather        = "ObsRoadweather"
+	KindObservationsAtRefTime = "ObservationsAtRefTime"
+)
+
+// JSONLHeader is the first line of a JSON Lines file, describing the records
+// on the following lines.
+type JSONLHeader struct {
+	Kind    string         `json:"kind"`
+	Version string         `json:"version"`
+	Created time.Time      `json:"created"`
+	Scheme  string         `json:"scheme,omitempty"`
+	Classes map[int]string `json:"classes,omitempty"`
+	Query   string         `json:"query,omitempty"` // ObsQuery.URL
+}
+
+// NewJSONLHeader describes records classified by scheme, which may be the
+// zero Scheme for raw observations, fetched with q.
+func NewJSONLHeader(scheme Scheme, q ObsQuery) JSONLHeader {
+	return JSONLHeader{
+		Version: Version,
+		Created: time.Now().UTC(),
+		Scheme:  scheme.Name,
+		Classes: scheme.Classes,
+		Query:   q.URL(),
+	}
+}
+
+// JSONLRecord is the type of the records in a JSON Lines file.
+type JSONLRecord interface {
+	ObsRoadweather | ObservationsAtRefTime
+}
+
+func jsonlKind[T JSONLRecord]() string {
+	var v T
+	switch any(v).(type) {
+	case ObsRoadweather:
+		return KindObsRoadweather
+	default:
+		return KindObservationsAtRefTime
+	}
+}
+
+// JSONLWriter writes a header and then one record per line.
+type JSONLWriter[T JSONLRecord] struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLWriter writes header to w, with Kind set from T.
+func NewJSONLWriter[T JSONLRecord](w io.Writer, header JSONLHeader) (*JSONLWriter[T], error) {
+	bw := bufio.NewWriter(w)
+	jw := &JSONLWriter[T]{w: bw, enc: json.NewEncoder(bw)}
+	jw.enc.SetEscapeHTML(false)
+	header.Kind = jsonlKind[T]()
+	if err := jw.enc.Encode(header); err != nil {
+		return nil, fmt.Errorf("writing JSONL header: %v", err)
+	}
+	return jw, nil
+}
+
+func (j *JSONLWriter[T]) Write(v T) error {
+	return j.enc.Encode(v)
+}
+
+// Flush writes the buffered records to the underlying writer.
+func (j *JSONLWriter[T]) Flush() error {
+	return j.w.Flush()
+}
+
+// JSONLReader reads the records written by JSONLWriter one at a time.
+type JSONLReader[T JSONLRecord] struct {
+	Header JSONLHeader
+	dec    *json.Decoder
+}
+
+// NewJSONLReader reads the header from r and checks that the file holds
+// records of type T.
+func NewJSONLReader[T JSONLRecord](r io.Reader) (*JSONLReader[T], error) {
+	jr := &JSONLReader[T]{dec: json.NewDecoder(bufio.NewReader(r))}
+	if err := jr.dec.Decode(&jr.Header); err != nil {
+		return nil, fmt.Errorf("reading JSONL header: %v", err)
+	}
+	if kind := jsonlKind[T](); jr.Header.Kind != kind {
+		return nil, fmt.Errorf("JSONL file holds %q, not %q", jr.Header.Kind, kind)
+	}
+	return jr, nil
+}
+
+// Read returns the next record, io.EOF after the last.
+func (j *JSONLReader[T]) Read() (T, error) {
+	var v T
+	err := j.dec.Decode(&v)
+	return v, err
+}
+
+// ReadAll returns the remaining records.
+func (j *JSONLReader[T]) ReadAll() ([]T, error) {
+	all := []T{}
+	for {
+		v, err := j.Read()
+		if err == io.EOF {
+			return all, nil
+		}
+		if err != nil {
+			return all, fmt.Errorf("reading JSONL record %d: %v", len(all)+1, err)
+		}
+		all = append(all, v)
+	}
+}
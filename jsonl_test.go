@@ -0,0 +1,166 @@
+package frostclient
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONLObsRoadweather(t *testing.T) {
+	refTime := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	obses := []ObsRoadweather{
+		{
+			RefTime:            refTime,
+			Station:            "SN12345:0",
+			FrostID:            "SN12345:0",
+			CamID:              7,
+			IceThickness:       0.25,
+			WaterFilmThickness: 0.1,
+			SnowThickness:      0,
+			Class:              WetAndIceE,
+			Present:            AllPresent,
+			IceSensor:          Sensor{TimeSeriesID: 0, PerformanceCategory: "C", ExposureCategory: "2", QualityCode: 0},
+			WaterFilmSensor:    Sensor{TimeSeriesID: 1, PerformanceCategory: "C", ExposureCategory: "2", QualityCode: 2},
+			SnowSensor:         Sensor{TimeSeriesID: 0, PerformanceCategory: "D", ExposureCategory: "1", QualityCode: QualityMissing},
+		},
+		{
+			RefTime:         refTime.Add(10 * time.Minute),
+			Station:         "SN12345:0",
+			FrostID:         "SN12345:0",
+			CamID:           7,
+			IceThickness:    0.3,
+			Class:           IceE,
+			Present:         IcePresent,
+			IceSensor:       Sensor{QualityCode: QualityMissing},
+			WaterFilmSensor: Sensor{QualityCode: QualityMissing},
+			SnowSensor:      Sensor{QualityCode: QualityMissing},
+		},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewJSONLWriter[ObsRoadweather](&buf, NewJSONLHeader(Scheme8, DefaultObsQuery))
+	if err != nil {
+		t.Fatalf("NewJSONLWriter: %v", err)
+	}
+	for _, obs := range obses {
+		if err := w.Write(obs); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	r, err := NewJSONLReader[ObsRoadweather](&buf)
+	if err != nil {
+		t.Fatalf("NewJSONLReader: %v", err)
+	}
+	if r.Header.Kind != KindObsRoadweather || r.Header.Scheme != Scheme8.Name || r.Header.Version != Version {
+		t.Errorf("header = %+v", r.Header)
+	}
+	if !reflect.DeepEqual(r.Header.Classes, Scheme8.Classes) {
+		t.Errorf("header classes = %v, want %v", r.Header.Classes, Scheme8.Classes)
+	}
+	got, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !reflect.DeepEqual(got, obses) {
+		t.Errorf("round trip\n got %+v\nwant %+v", got, obses)
+	}
+	if got[1].Complete() || got[1].Present != IcePresent {
+		t.Errorf("Present = %s, want Ice only", got[1].Present)
+	}
+}
+
+func TestJSONLObservationsAtRefTime(t *testing.T) {
+	records := []ObservationsAtRefTime{
+		{
+			SourceID:      "SN12345:0",
+			ReferenceTime: time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC),
+			Observations: []Observation{
+				{ElementID: "road_ice_thickness", Value: 0.25, Unit: "mm", TimeOffset: "PT0H", TimeResolution: "PT10M",
+					TimeSeriesID: 0, PerformanceCategory: "C", ExposureCategory: "2", QualityCode: 0},
+				{ElementID: "road_snow_thickness", Value: 0, Unit: "mm", TimeOffset: "PT0H", TimeResolution: "PT10M",
+					TimeSeriesID: 1, PerformanceCategory: "D", ExposureCategory: "1", QualityCode: QualityMissing},
+			},
+		},
+		{
+			SourceID:      "SN67890:1",
+			ReferenceTime: time.Date(2023, 2, 10, 12, 10, 0, 0, time.UTC),
+			Observations:  []Observation{},
+		},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewJSONLWriter[ObservationsAtRefTime](&buf, NewJSONLHeader(Scheme{}, DefaultObsQuery))
+	if err != nil {
+		t.Fatalf("NewJSONLWriter: %v", err)
+	}
+	for _, rec := range records {
+		if err := w.Write(rec); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	r, err := NewJSONLReader[ObservationsAtRefTime](&buf)
+	if err != nil {
+		t.Fatalf("NewJSONLReader: %v", err)
+	}
+	if r.Header.Kind != KindObservationsAtRefTime || r.Header.Query != DefaultObsQuery.URL() {
+		t.Errorf("header = %+v", r.Header)
+	}
+	got, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("round trip\n got %+v\nwant %+v", got, records)
+	}
+}
+
+// A Frost response without qualityCode reads as QualityMissing, not 0 (OK).
+func TestJSONLQualityCodeAbsent(t *testing.T) {
+	in := `{"kind":"ObservationsAtRefTime","version":"v0.1.0","created":"2023-02-10T12:00:00Z"}
+{"sourceId":"SN12345:0","referenceTime":"2023-02-10T12:00:00Z","observations":[{"elementId":"road_ice_thickness","value":0.1,"unit":"mm"}]}
+`
+	r, err := NewJSONLReader[ObservationsAtRefTime](strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("NewJSONLReader: %v", err)
+	}
+	rec, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if code := rec.Observations[0].QualityCode; code != QualityMissing {
+		t.Errorf("QualityCode = %d, want QualityMissing", code)
+	}
+}
+
+func TestJSONLWrongKind(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewJSONLWriter[ObsRoadweather](&buf, NewJSONLHeader(Scheme3, DefaultObsQuery))
+	if err != nil {
+		t.Fatalf("NewJSONLWriter: %v", err)
+	}
+	if err := w.Write(ObsRoadweather{Station: "SN12345:0", Present: AllPresent}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if _, err := NewJSONLReader[ObservationsAtRefTime](bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("reading ObsRoadweather records as ObservationsAtRefTime succeeded")
+	}
+
+	header := `{"kind":"Something","version":"v0.1.0","created":"2023-02-10T12:00:00Z"}` + "\n"
+	if _, err := NewJSONLReader[ObsRoadweather](strings.NewReader(header)); err == nil {
+		t.Error("reading a file of kind Something succeeded")
+	}
+}
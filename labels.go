@@ -0,0 +1,214 @@
+package frostclient
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/metno/roadlabels/pkg/db"
+)
+
+// Sensor labels are stored in the roadlabels database next to the human
+// labels, one row per camera, time and scheme. ts is Unix seconds UTC.
+
+// labelMigrations are applied in order by MigrateLabels and recorded in
+// frostclient_migrations. Never change one that is released, add a new one.
+var labelMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS sensor_labels (
+		cam_id     INTEGER NOT NULL,
+		ts         INTEGER NOT NULL,
+		scheme     TEXT    NOT NULL,
+		class      INTEGER NOT NULL,
+		class_name TEXT    NOT NULL,
+		ice        REAL,
+		water      REAL,
+		snow       REAL,
+		station    TEXT    NOT NULL,
+		source     TEXT    NOT NULL,
+		updated_at INTEGER NOT NULL,
+		PRIMARY KEY (cam_id, ts, scheme)
+	);
+	CREATE INDEX IF NOT EXISTS sensor_labels_ts ON sensor_labels (ts);`,
+}
+
+// MigrateLabels creates or upgrades the sensor label tables in conn.
+func MigrateLabels(conn *sql.DB) error {
+	_, err := conn.Exec(`CREATE TABLE IF NOT EXISTS frostclient_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating frostclient_migrations: %v", err)
+	}
+
+	var version int
+	err = conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM frostclient_migrations`).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("reading migration version: %v", err)
+	}
+	for v := version + 1; v <= len(labelMigrations); v++ {
+		tx, err := conn.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(labelMigrations[v-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %v", v, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO frostclient_migrations (version, applied_at) VALUES (?, ?)`, v, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %v", v, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %v", v, err)
+		}
+	}
+	return nil
+}
+
+// LabelStore writes and reads sensor labels in the roadlabels database.
+type LabelStore struct {
+	DB *sql.DB
+}
+
+// OpenLabelStore opens the database in path, db.DBFILE when empty, and
+// migrates it.
+func OpenLabelStore(path string) (*LabelStore, error) {
+	if path == "" {
+		path = db.DBFILE
+	}
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open(%s): %v", path, err)
+	}
+	if err := MigrateLabels(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &LabelStore{DB: conn}, nil
+}
+
+func (s *LabelStore) Close() error {
+	return s.DB.Close()
+}
+
+const upsertLabel = `INSERT INTO sensor_labels
+	(cam_id, ts, scheme, class, class_name, ice, water, snow, station, source, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (cam_id, ts, scheme) DO UPDATE SET
+		class = excluded.class,
+		class_name = excluded.class_name,
+		ice = excluded.ice,
+		water = excluded.water,
+		snow = excluded.snow,
+		station = excluded.station,
+		source = excluded.source,
+		updated_at = excluded.updated_at`
+
+// WriteLabels stores class2Obses, as returned by the GetDataFromFrost
+// functions, replacing earlier labels of the same camera, time and scheme.
+// It returns the number of rows written.
+func (s *LabelStore) WriteLabels(scheme Scheme, class2Obses map[int][]ObsRoadweather) (int, error) {
+	obses, names := []ObsRoadweather{}, []string{}
+	for _, o := range class2Obses {
+		for _, obs := range o {
+			obses = append(obses, obs)
+			names = append(names, scheme.Classes[obs.Class])
+		}
+	}
+	return s.write(scheme, obses, names)
+}
+
+// WriteNamedLabels stores name2Obses, as returned by GetObsMapForLabelApp,
+// with the map keys as class names.
+func (s *LabelStore) WriteNamedLabels(scheme Scheme, name2Obses map[string][]ObsRoadweather) (int, error) {
+	obses, names := []ObsRoadweather{}, []string{}
+	for name, o := range name2Obses {
+		for _, obs := range o {
+			obses = append(obses, obs)
+			names = append(names, name)
+		}
+	}
+	return s.write(scheme, obses, names)
+}
+
+// write upserts obses with the class names in names in one transaction, so
+// either all or none are written.
+func (s *LabelStore) write(scheme Scheme, obses []ObsRoadweather, names []string) (int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	stmt, err := tx.Prepare(upsertLabel)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("preparing upsert: %v", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().Unix()
+	for i, obs := range obses {
+		_, err := stmt.Exec(obs.CamID, obs.RefTime.Unix(), scheme.Name, obs.Class, names[i],
+			nullMM(obs, IcePresent, obs.IceThickness),
+			nullMM(obs, WaterFilmPresent, obs.WaterFilmThickness),
+			nullMM(obs, SnowPresent, obs.SnowThickness),
+			obs.Station, labelSource(obs), now)
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("writing label for camera %d at %s: %v", obs.CamID, obs.RefTime, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(obses), nil
+}
+
+// ReadLabels returns the sensor labels of scheme from from up to to, sorted
+// by camera and time, for CompareLabels. Values stored as NULL are left out
+// of Present. The sensors are not stored, only the source text.
+func (s *LabelStore) ReadLabels(scheme Scheme, from, to time.Time) ([]ObsRoadweather, error) {
+	rows, err := s.DB.Query(`SELECT cam_id, ts, class, ice, water, snow, station FROM sensor_labels
+		WHERE scheme = ? AND ts >= ? AND ts < ? ORDER BY cam_id, ts`, scheme.Name, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("query sensor labels: %v", err)
+	}
+	defer rows.Close()
+
+	obses := []ObsRoadweather{}
+	for rows.Next() {
+		var obs ObsRoadweather
+		var ts int64
+		var ice, water, snow sql.NullFloat64
+		if err := rows.Scan(&obs.CamID, &ts, &obs.Class, &ice, &water, &snow, &obs.Station); err != nil {
+			return obses, fmt.Errorf("scan sensor label: %v", err)
+		}
+		obs.RefTime = time.Unix(ts, 0).UTC()
+		obs.FrostID = obs.Station
+		obs.IceThickness = fromNullMM(&obs, IcePresent, ice)
+		obs.WaterFilmThickness = fromNullMM(&obs, WaterFilmPresent, water)
+		obs.SnowThickness = fromNullMM(&obs, SnowPresent, snow)
+		obses = append(obses, obs)
+	}
+	return obses, rows.Err()
+}
+
+// fromNullMM returns v and sets bit in the Present of obs unless v is NULL.
+func fromNullMM(obs *ObsRoadweather, bit ElementMask, v sql.NullFloat64) float32 {
+	if !v.Valid {
+		return 0
+	}
+	obs.Present |= bit
+	return float32(v.Float64)
+}
+
+// nullMM is NULL for missing values so they are not mistaken for 0 mm.
+func nullMM(obs ObsRoadweather, bit ElementMask, v float32) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: float64(v), Valid: obs.Present&bit != 0}
+}
+
+func labelSource(obs ObsRoadweather) string {
+	return fmt.Sprintf("frost %s ice=%s water=%s snow=%s frostclient %s",
+		obs.FrostID, obs.IceSensor, obs.WaterFilmSensor, obs.SnowSensor, Version)
+}
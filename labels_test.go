@@ -0,0 +1,143 @@
+package frostclient
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func openTestLabelStore(t *testing.T) *LabelStore {
+	t.Helper()
+	s, err := OpenLabelStore(filepath.Join(t.TempDir(), "roadcams.db"))
+	if err != nil {
+		t.Fatalf("OpenLabelStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestMigrateLabels(t *testing.T) {
+	s := openTestLabelStore(t)
+	// Migrating again is a no-op
+	for i := 0; i < 2; i++ {
+		if err := MigrateLabels(s.DB); err != nil {
+			t.Fatalf("MigrateLabels: %v", err)
+		}
+	}
+	var versions, max int
+	if err := s.DB.QueryRow(`SELECT COUNT(*), MAX(version) FROM frostclient_migrations`).Scan(&versions, &max); err != nil {
+		t.Fatal(err)
+	}
+	if versions != len(labelMigrations) || max != len(labelMigrations) {
+		t.Errorf("%d migrations recorded up to %d, want %d", versions, max, len(labelMigrations))
+	}
+}
+
+func TestWriteLabels(t *testing.T) {
+	s := openTestLabelStore(t)
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	class2Obses := map[int][]ObsRoadweather{
+		DryE: {{RefTime: at, Station: "SN1:0", FrostID: "SN1:0", CamID: 2, Class: DryE, Present: AllPresent}},
+		IceE: {
+			{RefTime: at, Station: "SN1:0", FrostID: "SN1:0", CamID: 1, IceThickness: 0.5, Class: IceE, Present: IcePresent},
+			{RefTime: at.Add(time.Hour), Station: "SN1:0", FrostID: "SN1:0", CamID: 1, IceThickness: 0.25, Class: IceE, Present: IcePresent},
+		},
+	}
+	if n, err := s.WriteLabels(Scheme8, class2Obses); n != 3 || err != nil {
+		t.Fatalf("WriteLabels = %d, %v, want 3", n, err)
+	}
+
+	// Upsert: the same camera, time and scheme is replaced, other schemes kept
+	wet := ObsRoadweather{RefTime: at, Station: "SN1:0", FrostID: "SN1:0", CamID: 1, WaterFilmThickness: 0.1, Class: WetE, Present: AllPresent}
+	if n, err := s.WriteLabels(Scheme8, map[int][]ObsRoadweather{WetE: {wet}}); n != 1 || err != nil {
+		t.Fatalf("WriteLabels = %d, %v, want 1", n, err)
+	}
+	if _, err := s.WriteLabels(Scheme3, map[int][]ObsRoadweather{Wet: {wet}}); err != nil {
+		t.Fatalf("WriteLabels: %v", err)
+	}
+
+	got, err := s.ReadLabels(Scheme8, at, at.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ReadLabels: %v", err)
+	}
+	want := []ObsRoadweather{
+		{RefTime: at, Station: "SN1:0", FrostID: "SN1:0", CamID: 1, WaterFilmThickness: 0.1, Class: WetE, Present: AllPresent},
+		{RefTime: at, Station: "SN1:0", FrostID: "SN1:0", CamID: 2, Class: DryE, Present: AllPresent},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadLabels\n got %+v\nwant %+v", got, want)
+	}
+
+	var names []string
+	rows, err := s.DB.Query(`SELECT class_name FROM sensor_labels WHERE scheme = ? ORDER BY ts, cam_id`, Scheme8.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	if want := []string{"Wet", "Dry", "Ice"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("class names %v, want %v", names, want)
+	}
+
+	// Missing values are NULL, not 0 mm
+	var waterNull, snowNull bool
+	err = s.DB.QueryRow(`SELECT water IS NULL, snow IS NULL FROM sensor_labels WHERE ts = ?`, at.Add(time.Hour).Unix()).Scan(&waterNull, &snowNull)
+	if err != nil || !waterNull || !snowNull {
+		t.Errorf("water and snow NULL = %v, %v, %v, want true", waterNull, snowNull, err)
+	}
+	got, err = s.ReadLabels(Scheme8, at.Add(time.Hour), at.Add(2*time.Hour))
+	if err != nil || len(got) != 1 || got[0].Present != IcePresent || got[0].IceThickness != 0.25 {
+		t.Errorf("ReadLabels = %+v, %v, want ice 0.25 only", got, err)
+	}
+}
+
+func TestWriteNamedLabels(t *testing.T) {
+	s := openTestLabelStore(t)
+	at := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	name2Obses := map[string][]ObsRoadweather{
+		"Water": {{RefTime: at, Station: "SN1:0", CamID: 1, Class: WetE, Present: AllPresent}},
+		"Ice":   {{RefTime: at, Station: "SN1:0", CamID: 2, IceThickness: 1, Class: IceE, Present: IcePresent}},
+		"Bad":   {{RefTime: at, Station: "SN1:0", CamID: 3, Class: DryE, Present: AllPresent}},
+	}
+	// All names are written in one transaction, so a failing row writes none
+	_, err := s.DB.Exec(`CREATE TRIGGER refuse_bad BEFORE INSERT ON sensor_labels
+		WHEN NEW.class_name = 'Bad' BEGIN SELECT RAISE(ABORT, 'bad label'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.WriteNamedLabels(Scheme8, name2Obses); err == nil || n != 0 {
+		t.Errorf("WriteNamedLabels = %d, %v, want an error", n, err)
+	}
+	var count int
+	s.DB.QueryRow(`SELECT COUNT(*) FROM sensor_labels`).Scan(&count)
+	if count != 0 {
+		t.Errorf("%d labels written by the failed write, want 0", count)
+	}
+
+	delete(name2Obses, "Bad")
+	if n, err := s.WriteNamedLabels(Scheme8, name2Obses); n != 2 || err != nil {
+		t.Fatalf("WriteNamedLabels = %d, %v, want 2", n, err)
+	}
+	rows, err := s.DB.Query(`SELECT cam_id, class_name, ice IS NULL, water IS NULL FROM sensor_labels ORDER BY cam_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	got := []string{}
+	for rows.Next() {
+		var cam int
+		var name string
+		var iceNull, waterNull bool
+		rows.Scan(&cam, &name, &iceNull, &waterNull)
+		got = append(got, fmt.Sprintf("%d %s %v %v", cam, name, iceNull, waterNull))
+	}
+	if want := []string{"1 Water false false", "2 Ice false true"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rows %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,251 @@
+package frostclient
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Splits are the names of the dataset splits, in order.
+var Splits = []string{"train", "val", "test"}
+
+// SplitBy is what is kept together in one split, so images of the same
+// station or days do not leak between training and evaluation.
+type SplitBy int
+
+const (
+	SplitByStation SplitBy = iota
+	SplitByDateBlock
+)
+
+func (s SplitBy) String() string {
+	if s == SplitByDateBlock {
+		return "dateblock"
+	}
+	return "station"
+}
+
+// SplitOptions control BuildManifest. Fractions are of the images, for
+// train, val and test.
+type SplitOptions struct {
+	By        SplitBy
+	BlockDays int // length of the date blocks
+	Fractions [3]float64
+	Seed      int64
+}
+
+var DefaultSplitOptions = SplitOptions{
+	By:        SplitByStation,
+	BlockDays: 7,
+	Fractions: [3]float64{0.7, 0.15, 0.15},
+}
+
+// ManifestEntry is one labelled image.
+type ManifestEntry struct {
+	Path         string    `json:"path"`
+	Label        int       `json:"label"`
+	ClassName    string    `json:"className"`
+	Split        string    `json:"split"`
+	CamID        int       `json:"camId"`
+	Station      string    `json:"station"`
+	Time         time.Time `json:"time"`
+	Interpolated bool      `json:"interpolated,omitempty"`
+}
+
+// Manifest is a split dataset of labelled images. Histograms counts the
+// images per split and class name.
+type Manifest struct {
+	Scheme     string                    `json:"scheme"`
+	Classes    map[int]string            `json:"classes"`
+	SplitBy    string                    `json:"splitBy"`
+	Seed       int64                     `json:"seed"`
+	Histograms map[string]map[string]int `json:"histograms"`
+	Entries    []ManifestEntry           `json:"entries"`
+}
+
+// BuildManifest splits labels, as from MatchImages, into train, val and test.
+// The images of a station, or of a block of days, all go to the same split.
+// The blocks are assigned in seeded random order to the split furthest below
+// its fraction, so the result only depends on the labels and opts.
+func BuildManifest(labels []ImageLabel, scheme Scheme, opts SplitOptions) Manifest {
+	if opts.BlockDays <= 0 {
+		opts.BlockDays = DefaultSplitOptions.BlockDays
+	}
+	if opts.Fractions == [3]float64{} {
+		opts.Fractions = DefaultSplitOptions.Fractions
+	}
+
+	groupOf := func(l ImageLabel) string {
+		if opts.By == SplitByDateBlock {
+			days := l.Image.Time.UTC().Unix() / (24 * 3600)
+			return strconv.FormatInt(days/int64(opts.BlockDays), 10)
+		}
+		return strings.Split(l.Obs.Station, ":")[0]
+	}
+	groups := make(map[string][]int)
+	for i, l := range labels {
+		g := groupOf(l)
+		groups[g] = append(groups[g], i)
+	}
+	keys := make([]string, 0, len(groups))
+	for g := range groups {
+		keys = append(keys, g)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		hi, hj := seededHash(opts.Seed, keys[i], time.Time{}), seededHash(opts.Seed, keys[j], time.Time{})
+		if hi != hj {
+			return hi < hj
+		}
+		return keys[i] < keys[j]
+	})
+
+	split := make([]string, len(labels))
+	counts := [3]int{}
+	for _, g := range keys {
+		best, bestDeficit := 0, 0.0
+		for s := range Splits {
+			deficit := opts.Fractions[s]*float64(len(labels)) - float64(counts[s])
+			if s == 0 || deficit > bestDeficit {
+				best, bestDeficit = s, deficit
+			}
+		}
+		for _, i := range groups[g] {
+			split[i] = Splits[best]
+		}
+		counts[best] += len(groups[g])
+	}
+
+	m := Manifest{
+		Scheme:     scheme.Name,
+		Classes:    scheme.Classes,
+		SplitBy:    opts.By.String(),
+		Seed:       opts.Seed,
+		Histograms: make(map[string]map[string]int),
+		Entries:    make([]ManifestEntry, 0, len(labels)),
+	}
+	for _, s := range Splits {
+		m.Histograms[s] = make(map[string]int)
+	}
+	for i, l := range labels {
+		e := ManifestEntry{
+			Path:         l.Image.Path,
+			Label:        l.Obs.Class,
+			ClassName:    scheme.Classes[l.Obs.Class],
+			Split:        split[i],
+			CamID:        l.Image.CamID,
+			Station:      l.Obs.Station,
+			Time:         l.Image.Time.UTC(),
+			Interpolated: l.Interpolated,
+		}
+		m.Entries = append(m.Entries, e)
+		m.Histograms[e.Split][e.ClassName]++
+	}
+	sort.SliceStable(m.Entries, func(i, j int) bool {
+		a, b := m.Entries[i], m.Entries[j]
+		if a.Split != b.Split {
+			return splitIndex(a.Split) < splitIndex(b.Split)
+		}
+		if a.CamID != b.CamID {
+			return a.CamID < b.CamID
+		}
+		return a.Time.Before(b.Time)
+	})
+	return m
+}
+
+func splitIndex(name string) int {
+	for i, s := range Splits {
+		if s == name {
+			return i
+		}
+	}
+	return len(Splits)
+}
+
+func (m Manifest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Manifest %s split by %s, seed %d\n", m.Scheme, m.SplitBy, m.Seed)
+	for _, s := range Splits {
+		names := make([]string, 0, len(m.Histograms[s]))
+		total := 0
+		for n, c := range m.Histograms[s] {
+			names = append(names, n)
+			total += c
+		}
+		sort.Strings(names)
+		fmt.Fprintf(&b, "  %s: %d\n", s, total)
+		for _, n := range names {
+			fmt.Fprintf(&b, "    %s: %d\n", n, m.Histograms[s][n])
+		}
+	}
+	return b.String()
+}
+
+// WriteCSV writes the entries of split, all when empty, with the path and
+// label first as image classification loaders expect.
+func (m Manifest) WriteCSV(w io.Writer, split string) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"path", "label", "className", "split", "camID", "station", "time", "interpolated"})
+	for _, e := range m.Entries {
+		if split != "" && e.Split != split {
+			continue
+		}
+		cw.Write([]string{
+			e.Path,
+			strconv.Itoa(e.Label),
+			e.ClassName,
+			e.Split,
+			strconv.Itoa(e.CamID),
+			e.Station,
+			e.Time.Format(time.RFC3339),
+			strconv.FormatBool(e.Interpolated),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (m Manifest) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// WriteFiles writes train.csv, val.csv, test.csv and manifest.json to dir
+// and returns their paths.
+func (m Manifest) WriteFiles(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("os.MkdirAll(%s): %v", dir, err)
+	}
+	files := []string{}
+	write := func(name string, fn func(io.Writer) error) error {
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("os.Create(%s): %v", path, err)
+		}
+		if err := fn(f); err != nil {
+			f.Close()
+			return fmt.Errorf("writing %s: %v", path, err)
+		}
+		files = append(files, path)
+		return f.Close()
+	}
+	for _, s := range Splits {
+		s := s
+		if err := write(s+".csv", func(w io.Writer) error { return m.WriteCSV(w, s) }); err != nil {
+			return files, err
+		}
+	}
+	if err := write("manifest.json", m.WriteJSON); err != nil {
+		return files, err
+	}
+	return files, nil
+}
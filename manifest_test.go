@@ -0,0 +1,154 @@
+package frostclient
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// manifestLabels returns n labels a day for each of stations, from start.
+func manifestLabels(start time.Time, days, n int, stations ...string) []ImageLabel {
+	labels := []ImageLabel{}
+	for s, station := range stations {
+		for d := 0; d < days; d++ {
+			for i := 0; i < n; i++ {
+				at := start.Add(time.Duration(d)*24*time.Hour + time.Duration(i)*time.Hour)
+				labels = append(labels, ImageLabel{
+					Image: Image{CamID: s + 1, Time: at, Path: fmt.Sprintf("%d/%s.jpg", s+1, at.Format("20060102T1504Z"))},
+					Obs:   ObsRoadweather{RefTime: at, Station: station + ":0", CamID: s + 1, Class: (s + i) % 2 * IceE},
+				})
+			}
+		}
+	}
+	return labels
+}
+
+func TestBuildManifestByStation(t *testing.T) {
+	start := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	stations := []string{"SN1", "SN2", "SN3", "SN4", "SN5", "SN6", "SN7", "SN8", "SN9", "SN10"}
+	labels := manifestLabels(start, 2, 5, stations...)
+	m := BuildManifest(labels, Scheme8, SplitOptions{Seed: 1})
+
+	if len(m.Entries) != len(labels) || m.SplitBy != "station" || m.Scheme != "8classes" {
+		t.Fatalf("manifest %s", m)
+	}
+	split := make(map[string]string)
+	counts := make(map[string]int)
+	for _, e := range m.Entries {
+		if s, ok := split[e.Station]; ok && s != e.Split {
+			t.Errorf("%s in %s and %s", e.Station, s, e.Split)
+		}
+		split[e.Station] = e.Split
+		counts[e.Split]++
+	}
+	// Ten stations of ten images, each to the split furthest below its fraction
+	if want := map[string]int{"train": 70, "val": 20, "test": 10}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("split sizes %v, want %v", counts, want)
+	}
+	for s, h := range m.Histograms {
+		if h["Dry"]+h["Ice"] != counts[s] {
+			t.Errorf("%s histogram %v, %d images", s, h, counts[s])
+		}
+	}
+
+	// Sorted by split, camera and time
+	for i := 1; i < len(m.Entries); i++ {
+		a, b := m.Entries[i-1], m.Entries[i]
+		if splitIndex(a.Split) > splitIndex(b.Split) ||
+			a.Split == b.Split && (a.CamID > b.CamID || a.CamID == b.CamID && !a.Time.Before(b.Time)) {
+			t.Errorf("entry %d %+v before %+v", i, a, b)
+		}
+	}
+
+	// The same seed splits the same, in any label order
+	reversed := make([]ImageLabel, len(labels))
+	for i, l := range labels {
+		reversed[len(labels)-1-i] = l
+	}
+	if again := BuildManifest(reversed, Scheme8, SplitOptions{Seed: 1}); !reflect.DeepEqual(again, m) {
+		t.Errorf("the same seed split differently")
+	}
+	if other := BuildManifest(labels, Scheme8, SplitOptions{Seed: 2}); reflect.DeepEqual(other.Entries, m.Entries) {
+		t.Errorf("seeds 1 and 2 split the same")
+	}
+}
+
+func TestBuildManifestByDateBlock(t *testing.T) {
+	start := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	labels := manifestLabels(start, 28, 2, "SN1", "SN2")
+	m := BuildManifest(labels, Scheme8, SplitOptions{By: SplitByDateBlock, BlockDays: 7, Fractions: [3]float64{0.5, 0.25, 0.25}})
+	if m.SplitBy != "dateblock" {
+		t.Errorf("split by %s", m.SplitBy)
+	}
+	block := make(map[int64]string)
+	counts := make(map[string]int)
+	for _, e := range m.Entries {
+		b := e.Time.Unix() / (7 * 24 * 3600)
+		if s, ok := block[b]; ok && s != e.Split {
+			t.Errorf("block %d in %s and %s", b, s, e.Split)
+		}
+		block[b] = e.Split
+		counts[e.Split]++
+	}
+	// Four weeks from a Wednesday span five blocks, as the blocks start on Thursdays
+	if counts["train"] < counts["val"] || counts["val"] == 0 || counts["test"] == 0 {
+		t.Errorf("split sizes %v", counts)
+	}
+}
+
+func TestManifestFiles(t *testing.T) {
+	start := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	labels := manifestLabels(start, 1, 2, "SN1", "SN2", "SN3", "SN4")
+	labels[0].Interpolated = true
+	m := BuildManifest(labels, Scheme8, DefaultSplitOptions)
+
+	var b bytes.Buffer
+	if err := m.WriteCSV(&b, "train"); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("reading back: %v", err)
+	}
+	if want := []string{"path", "label", "className", "split", "camID", "station", "time", "interpolated"}; !reflect.DeepEqual(records[0], want) {
+		t.Errorf("header %v, want %v", records[0], want)
+	}
+	if len(records) != 1+m.Histograms["train"]["Dry"]+m.Histograms["train"]["Ice"] {
+		t.Errorf("%d train rows, histogram %v", len(records)-1, m.Histograms["train"])
+	}
+	for _, r := range records[1:] {
+		if r[3] != "train" {
+			t.Errorf("row %v not in train", r)
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "manifest")
+	files, err := m.WriteFiles(dir)
+	if err != nil {
+		t.Fatalf("WriteFiles: %v", err)
+	}
+	want := []string{}
+	for _, name := range []string{"train.csv", "val.csv", "test.csv", "manifest.json"} {
+		want = append(want, filepath.Join(dir, name))
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("files %v, want %v", files, want)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Manifest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decoding manifest.json: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, m) {
+		t.Errorf("manifest.json\n%+v\nwant\n%+v", decoded, m)
+	}
+}
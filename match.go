@@ -0,0 +1,176 @@
+package frostclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/metno/roadlabels/pkg/db"
+)
+
+// IDScheme returns the identifiers a camera may be listed under in the
+// externalIds of a Frost source.
+type IDScheme func(cam db.Camera) []string
+
+// ForeignIDPrefix is the original scheme: the part of ForeignID before the
+// first "_", e.g. "100101_1" => "100101".
+func ForeignIDPrefix(cam db.Camera) []string {
+	idParts := strings.Split(cam.ForeignID, "_")
+	if idParts[0] == "" {
+		return nil
+	}
+	return []string{idParts[0]}
+}
+
+// ForeignIDFull uses the whole ForeignID.
+func ForeignIDFull(cam db.Camera) []string {
+	if cam.ForeignID == "" {
+		return nil
+	}
+	return []string{cam.ForeignID}
+}
+
+// ForeignIDNoLeadingZeros is ForeignIDPrefix with leading zeros removed, for
+// stations registered as "1234" in Frost and "001234_1" in the camera DB.
+func ForeignIDNoLeadingZeros(cam db.Camera) []string {
+	ids := ForeignIDPrefix(cam)
+	for i := range ids {
+		ids[i] = strings.TrimLeft(ids[i], "0")
+	}
+	if len(ids) == 1 && ids[0] == "" {
+		return nil
+	}
+	return ids
+}
+
+var DefaultIDSchemes = []IDScheme{ForeignIDPrefix}
+
+type StationMatch struct {
+	StationID   string // Frost source ID, e.g. SN12345
+	ExternalIDs []string
+	Cameras     []db.Camera
+}
+
+// AmbiguousMatch is a camera that matched more than one station.
+type AmbiguousMatch struct {
+	Camera   db.Camera
+	Stations []string
+}
+
+type MatchReport struct {
+	Matches           []StationMatch
+	UnmatchedCameras  []db.Camera
+	UnmatchedStations []string
+	Ambiguous         []AmbiguousMatch
+	Geo               []GeoMatch // Cameras added with AddGeoMatches
+}
+
+// MatchStations relates stations to cams by comparing the
+// identifiers produced by schemes with the stations' external IDs. A station
+// can have several cameras. Cameras matching several stations are kept in
+// all of them and listed in MatchReport.Ambiguous.
+func MatchStations(stations []Source, cams []db.Camera, schemes ...IDScheme) MatchReport {
+	if len(schemes) == 0 {
+		schemes = DefaultIDSchemes
+	}
+
+	idToCams := make(map[string][]int)
+	for c := 0; c < len(cams); c++ {
+		seen := make(map[string]bool)
+		for _, scheme := range schemes {
+			for _, id := range scheme(cams[c]) {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				idToCams[id] = append(idToCams[id], c)
+			}
+		}
+	}
+
+	report := MatchReport{}
+	camStations := make(map[int][]string)
+
+	for s := 0; s < len(stations); s++ {
+		match := StationMatch{StationID: stations[s].ID}
+		added := make(map[int]bool)
+		for f := 0; f < len(stations[s].ExternalIds); f++ {
+			extid := stations[s].ExternalIds[f]
+			camIdxs, ok := idToCams[extid]
+			if !ok {
+				continue
+			}
+			match.ExternalIDs = append(match.ExternalIDs, extid)
+			for _, c := range camIdxs {
+				if added[c] {
+					continue
+				}
+				added[c] = true
+				match.Cameras = append(match.Cameras, cams[c])
+				camStations[c] = append(camStations[c], stations[s].ID)
+			}
+		}
+		if len(match.Cameras) == 0 {
+			report.UnmatchedStations = append(report.UnmatchedStations, stations[s].ID)
+			continue
+		}
+		report.Matches = append(report.Matches, match)
+	}
+
+	for c := 0; c < len(cams); c++ {
+		stations := camStations[c]
+		if len(stations) == 0 {
+			report.UnmatchedCameras = append(report.UnmatchedCameras, cams[c])
+		} else if len(stations) > 1 {
+			report.Ambiguous = append(report.Ambiguous, AmbiguousMatch{Camera: cams[c], Stations: stations})
+		}
+	}
+
+	return report
+}
+
+// StationCameras returns the matched cameras keyed by station ID.
+func (r MatchReport) StationCameras() map[string][]db.Camera {
+	m := make(map[string][]db.Camera, len(r.Matches))
+	for _, match := range r.Matches {
+		m[match.StationID] = match.Cameras
+	}
+	return m
+}
+
+func (r MatchReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Matched stations: %d\n", len(r.Matches))
+	for _, m := range r.Matches {
+		camIDs := make([]string, 0, len(m.Cameras))
+		for _, cam := range m.Cameras {
+			camIDs = append(camIDs, fmt.Sprintf("%d", cam.ID))
+		}
+		fmt.Fprintf(&b, "  %s (%s): cams %s\n", m.StationID, strings.Join(m.ExternalIDs, ","), strings.Join(camIDs, ","))
+	}
+
+	fmt.Fprintf(&b, "Unmatched cameras: %d\n", len(r.UnmatchedCameras))
+	for _, cam := range r.UnmatchedCameras {
+		fmt.Fprintf(&b, "  %d ForeignID: %q\n", cam.ID, cam.ForeignID)
+	}
+
+	unmatched := append([]string(nil), r.UnmatchedStations...)
+	sort.Strings(unmatched)
+	fmt.Fprintf(&b, "Unmatched stations: %d\n", len(unmatched))
+	if len(unmatched) > 0 {
+		fmt.Fprintf(&b, "  %s\n", strings.Join(unmatched, ","))
+	}
+
+	fmt.Fprintf(&b, "Ambiguous cameras: %d\n", len(r.Ambiguous))
+	for _, a := range r.Ambiguous {
+		fmt.Fprintf(&b, "  %d ForeignID: %q stations: %s\n", a.Camera.ID, a.Camera.ForeignID, strings.Join(a.Stations, ","))
+	}
+
+	if len(r.Geo) > 0 {
+		fmt.Fprintf(&b, "Matched by location: %d\n", len(r.Geo))
+		for _, g := range r.Geo {
+			fmt.Fprintf(&b, "  %d => %s %.2f km, %.0f m\n", g.Camera.ID, g.StationID, g.Distance, g.AltitudeDiff)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package frostclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/metno/roadlabels/pkg/db"
+)
+
+func TestIDSchemes(t *testing.T) {
+	tests := []struct {
+		name      string
+		scheme    IDScheme
+		foreignID string
+		want      []string
+	}{
+		{"prefix", ForeignIDPrefix, "100101_1", []string{"100101"}},
+		{"prefix without suffix", ForeignIDPrefix, "100101", []string{"100101"}},
+		{"prefix empty", ForeignIDPrefix, "", nil},
+		{"prefix leading underscore", ForeignIDPrefix, "_1", nil},
+		{"full", ForeignIDFull, "100101_1", []string{"100101_1"}},
+		{"full empty", ForeignIDFull, "", nil},
+		{"no leading zeros", ForeignIDNoLeadingZeros, "001234_1", []string{"1234"}},
+		{"no leading zeros only zeros", ForeignIDNoLeadingZeros, "000_1", nil},
+		{"no leading zeros empty", ForeignIDNoLeadingZeros, "", nil},
+	}
+	for _, tt := range tests {
+		got := tt.scheme(db.Camera{ForeignID: tt.foreignID})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s(%q) = %q, want %q", tt.name, tt.foreignID, got, tt.want)
+		}
+	}
+}
+
+func TestMatchStations(t *testing.T) {
+	stations := []Source{
+		{ID: "SN1", ExternalIds: []string{"100101"}},
+		{ID: "SN2", ExternalIds: []string{"1234", "200202"}},
+		{ID: "SN3", ExternalIds: []string{"300303"}},
+		{ID: "SN4", ExternalIds: []string{"100101"}},
+	}
+	cams := []db.Camera{
+		{ID: 1, ForeignID: "100101_1"},
+		{ID: 2, ForeignID: "100101_2"},
+		{ID: 3, ForeignID: "001234_1"},
+		{ID: 4, ForeignID: "200202_1"},
+		{ID: 5, ForeignID: "999999_1"},
+		{ID: 6, ForeignID: ""},
+	}
+	camIDs := func(cams []db.Camera) []int {
+		ids := []int{}
+		for _, c := range cams {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name              string
+		schemes           []IDScheme
+		matches           map[string][]int
+		unmatchedCameras  []int
+		unmatchedStations []string
+		ambiguous         map[int][]string
+	}{
+		{
+			name:              "default prefix",
+			matches:           map[string][]int{"SN1": {1, 2}, "SN2": {4}, "SN4": {1, 2}},
+			unmatchedCameras:  []int{3, 5, 6},
+			unmatchedStations: []string{"SN3"},
+			ambiguous:         map[int][]string{1: {"SN1", "SN4"}, 2: {"SN1", "SN4"}},
+		},
+		{
+			name:              "prefix and no leading zeros",
+			schemes:           []IDScheme{ForeignIDPrefix, ForeignIDNoLeadingZeros},
+			matches:           map[string][]int{"SN1": {1, 2}, "SN2": {3, 4}, "SN4": {1, 2}},
+			unmatchedCameras:  []int{5, 6},
+			unmatchedStations: []string{"SN3"},
+			ambiguous:         map[int][]string{1: {"SN1", "SN4"}, 2: {"SN1", "SN4"}},
+		},
+		{
+			name:              "full ID matches nothing",
+			schemes:           []IDScheme{ForeignIDFull},
+			matches:           map[string][]int{},
+			unmatchedCameras:  []int{1, 2, 3, 4, 5, 6},
+			unmatchedStations: []string{"SN1", "SN2", "SN3", "SN4"},
+			ambiguous:         map[int][]string{},
+		},
+	}
+	for _, tt := range tests {
+		r := MatchStations(stations, cams, tt.schemes...)
+
+		matches := make(map[string][]int)
+		for id, cams := range r.StationCameras() {
+			matches[id] = camIDs(cams)
+		}
+		if !reflect.DeepEqual(matches, tt.matches) {
+			t.Errorf("%s: matches = %v, want %v", tt.name, matches, tt.matches)
+		}
+		if got := camIDs(r.UnmatchedCameras); !reflect.DeepEqual(got, tt.unmatchedCameras) {
+			t.Errorf("%s: unmatched cameras = %v, want %v", tt.name, got, tt.unmatchedCameras)
+		}
+		if !reflect.DeepEqual(r.UnmatchedStations, tt.unmatchedStations) {
+			t.Errorf("%s: unmatched stations = %v, want %v", tt.name, r.UnmatchedStations, tt.unmatchedStations)
+		}
+		ambiguous := make(map[int][]string)
+		for _, a := range r.Ambiguous {
+			ambiguous[a.Camera.ID] = a.Stations
+		}
+		if !reflect.DeepEqual(ambiguous, tt.ambiguous) {
+			t.Errorf("%s: ambiguous = %v, want %v", tt.name, ambiguous, tt.ambiguous)
+		}
+	}
+}
+
+func TestMatchReportString(t *testing.T) {
+	r := MatchStations(
+		[]Source{{ID: "SN1", ExternalIds: []string{"100101"}}, {ID: "SN2"}},
+		[]db.Camera{{ID: 1, ForeignID: "100101_1"}, {ID: 2, ForeignID: "5_1"}},
+	)
+	s := r.String()
+	for _, want := range []string{
+		"Matched stations: 1\n  SN1 (100101): cams 1\n",
+		"Unmatched cameras: 1\n  2 ForeignID: \"5_1\"\n",
+		"Unmatched stations: 1\n  SN2\n",
+		"Ambiguous cameras: 0\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("report is missing %q:\n%s", want, s)
+		}
+	}
+}
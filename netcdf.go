@@ -0,0 +1,454 @@
+package frostclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NetCDF export in the classic format with 64-bit offsets (CDF-2), written
+// without the C library. The file follows the CF conventions for a
+// timeSeries feature type in the orthogonal multidimensional representation:
+// every station has a value, or the fill value, at every time.
+
+// NetCDFOptions add metadata to WriteNetCDF.
+type NetCDFOptions struct {
+	// Elements from GetElements give the variables their names,
+	// descriptions and units. They are fetched from Frost when nil, and the
+	// values are written in mm if that fails.
+	Elements map[string]Element
+	// Sources are the stations with their coordinates, e.g. the Data of
+	// GetSVVStations, the sources it last fetched when nil.
+	Sources []Source
+	// Locate returns the metadata of a station observed from from to to.
+	// When nil it is the record from Catalog, if loaded, or else the one
+	// from Sources.
+	Locate func(station string, from, to time.Time) (StationRecord, bool)
+	Title  string
+}
+
+// sourceLocator returns the Catalog record of a station, if the catalog is
+// loaded, or else its record from sources, or from the sources last fetched
+// by GetSVVStations when nil.
+func sourceLocator(sources []Source) func(station string, from, to time.Time) (StationRecord, bool) {
+	byID := svvSources
+	if sources != nil {
+		byID = make(map[string]Source)
+		for _, src := range sources {
+			byID[src.ID] = src
+		}
+	}
+	return func(station string, from, to time.Time) (StationRecord, bool) {
+		if Catalog != nil {
+			if rec, ok := Catalog.ValidIn(station, from, to); ok {
+				return rec, true
+			}
+		}
+		src, ok := byID[strings.Split(station, ":")[0]]
+		if !ok || len(src.Geometry.Coordinates) < 2 {
+			return StationRecord{}, false
+		}
+		return recordOf(src, time.Now().UTC()), true
+	}
+}
+
+const (
+	ncChar   = 2
+	ncInt    = 4
+	ncFloat  = 5
+	ncDouble = 6
+
+	ncDimension = 0x0a
+	ncVariable  = 0x0b
+	ncAttribute = 0x0c
+
+	ncFillFloat = float32(9.9692099683868690e+36)
+	ncFillClass = int32(-1)
+)
+
+type ncAttr struct {
+	name  string
+	value any // string, int32, []int32, float32 or float64
+}
+
+type ncVar struct {
+	name  string
+	dims  []int
+	typ   int32
+	attrs []ncAttr
+	write func(w io.Writer) error
+}
+
+// WriteNetCDF writes class2Obses as a CF timeSeries NetCDF file with one
+// series per station. Observations repeated for several cameras are written
+// once.
+func WriteNetCDF(w io.Writer, scheme Scheme, class2Obses map[int][]ObsRoadweather, opts NetCDFOptions) error {
+	type key struct {
+		station string
+		t       int64
+	}
+	values := make(map[key]ObsRoadweather)
+	stationSet := make(map[string]bool)
+	timeSet := make(map[int64]bool)
+	for _, obses := range class2Obses {
+		for _, obs := range obses {
+			k := key{obs.Station, obs.RefTime.Unix()}
+			if _, ok := values[k]; ok {
+				continue
+			}
+			values[k] = obs
+			stationSet[obs.Station] = true
+			timeSet[k.t] = true
+		}
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("WriteNetCDF: no observations")
+	}
+
+	stations := make([]string, 0, len(stationSet))
+	strlen := 1
+	for s := range stationSet {
+		stations = append(stations, s)
+		if len(s) > strlen {
+			strlen = len(s)
+		}
+	}
+	sort.Strings(stations)
+	times := make([]int64, 0, len(timeSet))
+	for t := range timeSet {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	locate := opts.Locate
+	if locate == nil {
+		locate = sourceLocator(opts.Sources)
+	}
+	from, to := time.Unix(times[0], 0).UTC(), time.Unix(times[len(times)-1]+1, 0).UTC()
+	lat := make([]float64, len(stations))
+	lon := make([]float64, len(stations))
+	alt := make([]float64, len(stations))
+	unknown := []string{}
+	for i, s := range stations {
+		rec, ok := locate(s, from, to)
+		if !ok {
+			unknown = append(unknown, s)
+			continue
+		}
+		lat[i], lon[i], alt[i] = rec.Latitude, rec.Longitude, float64(rec.Masl)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("WriteNetCDF: no coordinates for %d stations: %s", len(unknown), strings.Join(unknown, ", "))
+	}
+
+	elements := opts.Elements
+	if elements == nil {
+		var err error
+		elements, err = GetElements(RoadweatherElements...)
+		if err != nil {
+			log.Printf("WriteNetCDF: GetElements: %v. Writing mm", err)
+		}
+	}
+
+	coordinates := ncAttr{"coordinates", "time lat lon alt station_name"}
+	vars := []ncVar{
+		{name: "time", dims: []int{1}, typ: ncDouble, attrs: []ncAttr{
+			{"standard_name", "time"},
+			{"long_name", "reference time of the observation"},
+			{"units", "seconds since 1970-01-01 00:00:00 UTC"},
+			{"calendar", "standard"},
+			{"axis", "T"},
+		}, write: func(w io.Writer) error {
+			t := make([]float64, len(times))
+			for i := range times {
+				t[i] = float64(times[i])
+			}
+			return binary.Write(w, binary.BigEndian, t)
+		}},
+		{name: "lat", dims: []int{0}, typ: ncDouble, attrs: []ncAttr{
+			{"standard_name", "latitude"},
+			{"long_name", "station latitude"},
+			{"units", "degrees_north"},
+		}, write: func(w io.Writer) error { return binary.Write(w, binary.BigEndian, lat) }},
+		{name: "lon", dims: []int{0}, typ: ncDouble, attrs: []ncAttr{
+			{"standard_name", "longitude"},
+			{"long_name", "station longitude"},
+			{"units", "degrees_east"},
+		}, write: func(w io.Writer) error { return binary.Write(w, binary.BigEndian, lon) }},
+		{name: "alt", dims: []int{0}, typ: ncDouble, attrs: []ncAttr{
+			{"standard_name", "altitude"},
+			{"long_name", "station height above mean sea level"},
+			{"units", "m"},
+			{"positive", "up"},
+			{"axis", "Z"},
+		}, write: func(w io.Writer) error { return binary.Write(w, binary.BigEndian, alt) }},
+		{name: "station_name", dims: []int{0, 2}, typ: ncChar, attrs: []ncAttr{
+			{"long_name", "Frost source ID"},
+			{"cf_role", "timeseries_id"},
+		}, write: func(w io.Writer) error {
+			for _, s := range stations {
+				b := make([]byte, strlen)
+				copy(b, s)
+				if _, err := w.Write(b); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	}
+
+	elementValue := map[string]func(ObsRoadweather) float32{
+		"road_ice_thickness":        func(o ObsRoadweather) float32 { return o.IceThickness },
+		"road_water_film_thickness": func(o ObsRoadweather) float32 { return o.WaterFilmThickness },
+		"road_snow_thickness":       func(o ObsRoadweather) float32 { return o.SnowThickness },
+	}
+	elementBit := map[string]ElementMask{
+		"road_ice_thickness":        IcePresent,
+		"road_water_film_thickness": WaterFilmPresent,
+		"road_snow_thickness":       SnowPresent,
+	}
+	for _, id := range RoadweatherElements {
+		id := id
+		attrs := []ncAttr{{"long_name", strings.ReplaceAll(id, "_", " ")}}
+		unit, perUnit := "mm", float32(1)
+		if e, ok := elements[id]; ok {
+			attrs = []ncAttr{{"long_name", e.Name}, {"comment", e.Description}}
+			if f, ok := mmPerUnit[strings.TrimSpace(e.Unit)]; ok {
+				unit, perUnit = strings.TrimSpace(e.Unit), f
+			} else if e.Unit != "" {
+				log.Printf("WriteNetCDF: %s in unsupported unit %q, writing mm", id, e.Unit)
+			}
+		}
+		attrs = append(attrs,
+			ncAttr{"units", unit},
+			ncAttr{"frost_element_id", id},
+			ncAttr{"_FillValue", ncFillFloat},
+			coordinates,
+		)
+		vars = append(vars, ncVar{name: id, dims: []int{0, 1}, typ: ncFloat, attrs: attrs,
+			write: func(w io.Writer) error {
+				row := make([]float32, len(times))
+				for _, s := range stations {
+					for i, t := range times {
+						obs, ok := values[key{s, t}]
+						if !ok || obs.Present&elementBit[id] == 0 {
+							row[i] = ncFillFloat
+							continue
+						}
+						row[i] = elementValue[id](obs) / perUnit
+					}
+					if err := binary.Write(w, binary.BigEndian, row); err != nil {
+						return err
+					}
+				}
+				return nil
+			}})
+	}
+
+	classes := make([]int, 0, len(scheme.Classes))
+	for c := range scheme.Classes {
+		classes = append(classes, c)
+	}
+	sort.Ints(classes)
+	flagValues := make([]int32, len(classes))
+	flagMeanings := make([]string, len(classes))
+	for i, c := range classes {
+		flagValues[i] = int32(c)
+		flagMeanings[i] = cfFlagMeaning(scheme.Classes[c])
+	}
+	vars = append(vars, ncVar{name: "class", dims: []int{0, 1}, typ: ncInt, attrs: []ncAttr{
+		{"long_name", "road condition class, " + scheme.Name},
+		{"_FillValue", ncFillClass},
+		{"flag_values", flagValues},
+		{"flag_meanings", strings.Join(flagMeanings, " ")},
+		coordinates,
+	}, write: func(w io.Writer) error {
+		row := make([]int32, len(times))
+		for _, s := range stations {
+			for i, t := range times {
+				obs, ok := values[key{s, t}]
+				if !ok {
+					row[i] = ncFillClass
+					continue
+				}
+				row[i] = int32(obs.Class)
+			}
+			if err := binary.Write(w, binary.BigEndian, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}})
+
+	title := opts.Title
+	if title == "" {
+		title = "Road weather observations from Frost"
+	}
+	globals := []ncAttr{
+		{"Conventions", "CF-1.8"},
+		{"featureType", "timeSeries"},
+		{"title", title},
+		{"source", "Frost API, https://frost.met.no"},
+		{"history", time.Now().UTC().Format(time.RFC3339) + " created by frostclient-roadweather " + Version},
+		{"classification_scheme", scheme.Name},
+	}
+	dims := []struct {
+		name string
+		size int
+	}{{"station", len(stations)}, {"time", len(times)}, {"name_strlen", strlen}}
+
+	size := func(v ncVar) int64 {
+		n := int64(ncTypeSize(v.typ))
+		for _, d := range v.dims {
+			n *= int64(dims[d].size)
+		}
+		return (n + 3) &^ 3
+	}
+
+	header := func(begins []int64) []byte {
+		var b bytes.Buffer
+		b.WriteString("CDF\x02")
+		ncInt32(&b, 0) // numrecs, no record dimension
+		ncInt32(&b, ncDimension)
+		ncInt32(&b, int32(len(dims)))
+		for _, d := range dims {
+			ncName(&b, d.name)
+			ncInt32(&b, int32(d.size))
+		}
+		ncAttrs(&b, globals)
+		ncInt32(&b, ncVariable)
+		ncInt32(&b, int32(len(vars)))
+		for i, v := range vars {
+			ncName(&b, v.name)
+			ncInt32(&b, int32(len(v.dims)))
+			for _, d := range v.dims {
+				ncInt32(&b, int32(d))
+			}
+			ncAttrs(&b, v.attrs)
+			ncInt32(&b, v.typ)
+			vsize := size(v)
+			if vsize > math.MaxUint32 {
+				vsize = math.MaxUint32 // The real size follows from the dimensions
+			}
+			ncInt32(&b, int32(uint32(vsize)))
+			binary.Write(&b, binary.BigEndian, begins[i])
+		}
+		return b.Bytes()
+	}
+
+	begins := make([]int64, len(vars))
+	offset := int64(len(header(begins)))
+	for i, v := range vars {
+		begins[i] = offset
+		offset += size(v)
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(header(begins)); err != nil {
+		return err
+	}
+	for _, v := range vars {
+		if err := v.write(bw); err != nil {
+			return fmt.Errorf("writing %s: %v", v.name, err)
+		}
+		written := int64(ncTypeSize(v.typ))
+		for _, d := range v.dims {
+			written *= int64(dims[d].size)
+		}
+		if pad := size(v) - written; pad > 0 {
+			bw.Write(make([]byte, pad))
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteNetCDFFile writes class2Obses to the file path, see WriteNetCDF.
+func WriteNetCDFFile(path string, scheme Scheme, class2Obses map[int][]ObsRoadweather, opts NetCDFOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("os.Create(%s): %v", path, err)
+	}
+	if err := WriteNetCDF(f, scheme, class2Obses, opts); err != nil {
+		f.Close()
+		return fmt.Errorf("WriteNetCDF(%s): %v", path, err)
+	}
+	return f.Close()
+}
+
+// cfFlagMeaning makes a class name a valid word of a CF flag_meanings list.
+func cfFlagMeaning(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.+@", r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func ncTypeSize(typ int32) int {
+	switch typ {
+	case ncChar:
+		return 1
+	case ncDouble:
+		return 8
+	}
+	return 4
+}
+
+func ncInt32(b *bytes.Buffer, v int32) {
+	binary.Write(b, binary.BigEndian, v)
+}
+
+func ncPad(b *bytes.Buffer, n int) {
+	b.Write(make([]byte, (4-n%4)%4))
+}
+
+func ncName(b *bytes.Buffer, name string) {
+	ncInt32(b, int32(len(name)))
+	b.WriteString(name)
+	ncPad(b, len(name))
+}
+
+func ncAttrs(b *bytes.Buffer, attrs []ncAttr) {
+	if len(attrs) == 0 {
+		ncInt32(b, 0) // ABSENT
+		ncInt32(b, 0)
+		return
+	}
+	ncInt32(b, ncAttribute)
+	ncInt32(b, int32(len(attrs)))
+	for _, a := range attrs {
+		ncName(b, a.name)
+		switch v := a.value.(type) {
+		case string:
+			ncInt32(b, ncChar)
+			ncInt32(b, int32(len(v)))
+			b.WriteString(v)
+			ncPad(b, len(v))
+		case int32:
+			ncInt32(b, ncInt)
+			ncInt32(b, 1)
+			ncInt32(b, v)
+		case []int32:
+			ncInt32(b, ncInt)
+			ncInt32(b, int32(len(v)))
+			binary.Write(b, binary.BigEndian, v)
+		case float32:
+			ncInt32(b, ncFloat)
+			ncInt32(b, 1)
+			binary.Write(b, binary.BigEndian, v)
+		case float64:
+			ncInt32(b, ncDouble)
+			ncInt32(b, 1)
+			binary.Write(b, binary.BigEndian, v)
+		}
+	}
+}
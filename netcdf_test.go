@@ -0,0 +1,295 @@
+package frostclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// cdf is a NetCDF classic file as read by readCDF2, a reader written from
+// the format specification, independent of WriteNetCDF.
+type cdf struct {
+	magic   string
+	numrecs int32
+	dims    []cdfDim
+	attrs   map[string]any
+	vars    []cdfVar
+	header  int64 // length of the header
+	size    int64
+	data    []byte
+}
+
+type cdfDim struct {
+	name string
+	len  int32
+}
+
+type cdfVar struct {
+	name   string
+	dimids []int32
+	attrs  map[string]any
+	typ    int32
+	vsize  int32
+	begin  int64
+}
+
+type cdfReader struct {
+	b   []byte
+	off int
+	err error
+}
+
+func (r *cdfReader) next(n int) []byte {
+	if r.err != nil || r.off+n > len(r.b) {
+		if r.err == nil {
+			r.err = fmt.Errorf("unexpected end of file at %d", r.off)
+		}
+		return make([]byte, n)
+	}
+	b := r.b[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *cdfReader) int32() int32 { return int32(binary.BigEndian.Uint32(r.next(4))) }
+func (r *cdfReader) int64() int64 { return int64(binary.BigEndian.Uint64(r.next(8))) }
+
+// padded reads n bytes padded to a multiple of 4.
+func (r *cdfReader) padded(n int) []byte {
+	b := r.next(n)
+	r.next((4 - n%4) % 4)
+	return b
+}
+
+func (r *cdfReader) name() string {
+	return string(r.padded(int(r.int32())))
+}
+
+// values reads n values of the external type typ.
+func (r *cdfReader) values(typ int32, n int) any {
+	switch typ {
+	case 2: // NC_CHAR
+		return string(r.padded(n))
+	case 4: // NC_INT
+		v := make([]int32, n)
+		for i := range v {
+			v[i] = r.int32()
+		}
+		return v
+	case 5: // NC_FLOAT
+		v := make([]float32, n)
+		for i := range v {
+			v[i] = math.Float32frombits(uint32(r.int32()))
+		}
+		return v
+	case 6: // NC_DOUBLE
+		v := make([]float64, n)
+		for i := range v {
+			v[i] = math.Float64frombits(uint64(r.int64()))
+		}
+		return v
+	}
+	r.err = fmt.Errorf("unsupported type %d", typ)
+	return nil
+}
+
+func (r *cdfReader) attrs() map[string]any {
+	attrs := make(map[string]any)
+	tag, n := r.int32(), r.int32()
+	if tag == 0 && n == 0 {
+		return attrs
+	}
+	if tag != 0x0c {
+		r.err = fmt.Errorf("attribute list tag %#x", tag)
+	}
+	for i := int32(0); i < n && r.err == nil; i++ {
+		name := r.name()
+		typ := r.int32()
+		attrs[name] = r.values(typ, int(r.int32()))
+	}
+	return attrs
+}
+
+func readCDF2(b []byte) (cdf, error) {
+	r := &cdfReader{b: b}
+	f := cdf{magic: string(r.next(4)), numrecs: r.int32(), size: int64(len(b)), data: b}
+	tag, n := r.int32(), r.int32()
+	if tag != 0x0a && !(tag == 0 && n == 0) {
+		return f, fmt.Errorf("dimension list tag %#x", tag)
+	}
+	for i := int32(0); i < n && r.err == nil; i++ {
+		f.dims = append(f.dims, cdfDim{r.name(), r.int32()})
+	}
+	f.attrs = r.attrs()
+	tag, n = r.int32(), r.int32()
+	if tag != 0x0b && !(tag == 0 && n == 0) {
+		return f, fmt.Errorf("variable list tag %#x", tag)
+	}
+	for i := int32(0); i < n && r.err == nil; i++ {
+		v := cdfVar{name: r.name()}
+		ndims := r.int32()
+		for j := int32(0); j < ndims; j++ {
+			v.dimids = append(v.dimids, r.int32())
+		}
+		v.attrs = r.attrs()
+		v.typ = r.int32()
+		v.vsize = r.int32()
+		v.begin = r.int64()
+		f.vars = append(f.vars, v)
+	}
+	f.header = int64(r.off)
+	return f, r.err
+}
+
+func (f cdf) variable(name string) (cdfVar, bool) {
+	for _, v := range f.vars {
+		if v.name == name {
+			return v, true
+		}
+	}
+	return cdfVar{}, false
+}
+
+// read returns the values of the variable name.
+func (f cdf) read(name string) (any, error) {
+	v, ok := f.variable(name)
+	if !ok {
+		return nil, fmt.Errorf("no variable %s", name)
+	}
+	n := 1
+	for _, d := range v.dimids {
+		n *= int(f.dims[d].len)
+	}
+	r := &cdfReader{b: f.data, off: int(v.begin)}
+	values := r.values(v.typ, n)
+	return values, r.err
+}
+
+func TestWriteNetCDF(t *testing.T) {
+	defer func(c *StationCatalog) { Catalog = c }(Catalog)
+	Catalog = nil
+
+	t0 := time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)
+	t1, t2 := t0.Add(10*time.Minute), t0.Add(20*time.Minute)
+	class2Obses := map[int][]ObsRoadweather{
+		DryE: {
+			{RefTime: t0, Station: "SN2:0", CamID: 1, Class: DryE, Present: AllPresent},
+			{RefTime: t0, Station: "SN2:0", CamID: 2, Class: DryE, Present: AllPresent}, // second camera
+			{RefTime: t2, Station: "SN10:0", CamID: 3, Class: DryE, Present: AllPresent},
+		},
+		IceE: {
+			{RefTime: t1, Station: "SN2:0", CamID: 1, IceThickness: 0.5, SnowThickness: 20, Class: IceE, Present: IcePresent | SnowPresent},
+			{RefTime: t0, Station: "SN10:0", CamID: 3, IceThickness: 1.25, Class: IceE, Present: AllPresent},
+		},
+	}
+	opts := NetCDFOptions{
+		Elements: map[string]Element{
+			"road_ice_thickness":        {ID: "road_ice_thickness", Name: "Ice", Unit: "mm"},
+			"road_water_film_thickness": {ID: "road_water_film_thickness", Name: "Water film", Unit: "mm"},
+			"road_snow_thickness":       {ID: "road_snow_thickness", Name: "Snow", Unit: "cm"},
+		},
+		Sources: []Source{source("SN2", 60.5, 10.25, 120), source("SN10", 69.75, 18.5, 15)},
+		Title:   "Test",
+	}
+	var b bytes.Buffer
+	if err := WriteNetCDF(&b, Scheme8, class2Obses, opts); err != nil {
+		t.Fatalf("WriteNetCDF: %v", err)
+	}
+
+	f, err := readCDF2(b.Bytes())
+	if err != nil {
+		t.Fatalf("readCDF2: %v", err)
+	}
+	if f.magic != "CDF\x02" || f.numrecs != 0 {
+		t.Errorf("magic %q, numrecs %d", f.magic, f.numrecs)
+	}
+	if want := []cdfDim{{"station", 2}, {"time", 3}, {"name_strlen", 6}}; !reflect.DeepEqual(f.dims, want) {
+		t.Errorf("dims %v, want %v", f.dims, want)
+	}
+	for name, want := range map[string]any{"Conventions": "CF-1.8", "featureType": "timeSeries", "title": "Test", "classification_scheme": "8classes"} {
+		if got := f.attrs[name]; got != want {
+			t.Errorf("global %s = %v, want %v", name, got, want)
+		}
+	}
+
+	// The variables follow the header back to back, each padded to 4 bytes
+	names := []string{}
+	begin := f.header
+	for _, v := range f.vars {
+		names = append(names, v.name)
+		if v.begin != begin {
+			t.Errorf("%s begins at %d, want %d", v.name, v.begin, begin)
+		}
+		if v.vsize%4 != 0 {
+			t.Errorf("%s vsize %d not padded", v.name, v.vsize)
+		}
+		begin += int64(v.vsize)
+	}
+	if begin != f.size {
+		t.Errorf("variables end at %d, file is %d bytes", begin, f.size)
+	}
+	wantNames := []string{"time", "lat", "lon", "alt", "station_name", "road_ice_thickness", "road_water_film_thickness", "road_snow_thickness", "class"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("variables %v, want %v", names, wantNames)
+	}
+
+	fill := ncFillFloat
+	tests := []struct {
+		name string
+		want any
+	}{
+		{"time", []float64{float64(t0.Unix()), float64(t1.Unix()), float64(t2.Unix())}},
+		{"lat", []float64{69.75, 60.5}}, // sorted SN10:0, SN2:0
+		{"lon", []float64{18.5, 10.25}},
+		{"alt", []float64{15, 120}},
+		{"station_name", "SN10:0SN2:0\x00"},
+		{"road_ice_thickness", []float32{1.25, fill, 0, 0, 0.5, fill}},
+		{"road_water_film_thickness", []float32{0, fill, 0, 0, fill, fill}},
+		{"road_snow_thickness", []float32{0, fill, 0, 0, 2, fill}}, // cm
+		{"class", []int32{3, -1, 0, 0, 3, -1}},                     // Ice, none, Dry, Dry, Ice, none
+	}
+	for _, tt := range tests {
+		got, err := f.read(tt.name)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	snow, _ := f.variable("road_snow_thickness")
+	class, _ := f.variable("class")
+	attrs := []struct {
+		v    cdfVar
+		name string
+		want any
+	}{
+		{snow, "units", "cm"},
+		{snow, "long_name", "Snow"},
+		{snow, "_FillValue", []float32{fill}},
+		{snow, "coordinates", "time lat lon alt station_name"},
+		{class, "_FillValue", []int32{-1}},
+		{class, "flag_values", []int32{0, 1, 2, 3, 4, 5, 6, 7}},
+		{class, "flag_meanings", "Dry Wet Snow Ice Wet+Snow Wet+Ice Snow+Ice Snow+Ice+Wet"},
+	}
+	for _, tt := range attrs {
+		if got := tt.v.attrs[tt.name]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:%s = %v, want %v", tt.v.name, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteNetCDFErrors(t *testing.T) {
+	defer func(c *StationCatalog) { Catalog = c }(Catalog)
+	Catalog = nil
+	obs := ObsRoadweather{RefTime: time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC), Station: "SN3:0", Present: AllPresent}
+	opts := NetCDFOptions{Elements: map[string]Element{}, Sources: []Source{source("SN2", 60, 10, 0)}}
+	if err := WriteNetCDF(&bytes.Buffer{}, Scheme8, nil, opts); err == nil {
+		t.Errorf("no observations: no error")
+	}
+	if err := WriteNetCDF(&bytes.Buffer{}, Scheme8, map[int][]ObsRoadweather{DryE: {obs}}, opts); err == nil {
+		t.Errorf("station without coordinates: no error")
+	}
+}
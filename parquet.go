@@ -0,0 +1,583 @@
+package frostclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// The Parquet files have the columns of CSVHeader, refTime as a UTC
+// millisecond timestamp, the thicknesses as optional float32, null when
+// missing, and the strings dictionary encoded. Pages are not compressed.
+// Only the parts of the format needed for this are implemented, so it builds
+// without dependencies. The refcheck module reads the files back with a
+// reference reader.
+
+// ParquetOptions size the row groups and pages of a Parquet file.
+type ParquetOptions struct {
+	RowGroupRows int
+	PageRows     int
+}
+
+var DefaultParquetOptions = ParquetOptions{
+	RowGroupRows: 1 << 20,
+	PageRows:     1 << 16,
+}
+
+// Parquet physical types, encodings and the other enums used from
+// parquet.thrift.
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetFloat     = 4
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain           = 0
+	encodingPlainDictionary = 2
+	encodingRLE             = 3
+
+	pageData       = 0
+	pageDictionary = 2
+)
+
+type parquetRow struct {
+	obs       ObsRoadweather
+	className string
+	scheme    string
+}
+
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // -1 => none
+	timestamp bool  // logical type TIMESTAMP(UTC, MILLIS)
+	plain     func(b *bytes.Buffer, r parquetRow)
+	str       func(r parquetRow) string // dictionary encoded when set
+	present   func(r parquetRow) bool   // optional, null when false, when set
+}
+
+func presentIn(bit ElementMask) func(r parquetRow) bool {
+	return func(r parquetRow) bool { return r.obs.Present&bit != 0 }
+}
+
+func plainInt32(b *bytes.Buffer, v int32) {
+	binary.Write(b, binary.LittleEndian, v)
+}
+
+func plainFloat(b *bytes.Buffer, v float32) {
+	binary.Write(b, binary.LittleEndian, math.Float32bits(v))
+}
+
+var parquetColumns = []parquetColumn{
+	{name: "refTime", typ: parquetInt64, converted: convertedTimestampMillis, timestamp: true,
+		plain: func(b *bytes.Buffer, r parquetRow) { binary.Write(b, binary.LittleEndian, r.obs.RefTime.UnixMilli()) }},
+	{name: "station", typ: parquetByteArray, converted: convertedUTF8, str: func(r parquetRow) string { return r.obs.Station }},
+	{name: "frostID", typ: parquetByteArray, converted: convertedUTF8, str: func(r parquetRow) string { return r.obs.FrostID }},
+	{name: "camID", typ: parquetInt32, converted: -1, plain: func(b *bytes.Buffer, r parquetRow) { plainInt32(b, int32(r.obs.CamID)) }},
+	{name: "ice", typ: parquetFloat, converted: -1, present: presentIn(IcePresent),
+		plain: func(b *bytes.Buffer, r parquetRow) { plainFloat(b, r.obs.IceThickness) }},
+	{name: "water", typ: parquetFloat, converted: -1, present: presentIn(WaterFilmPresent),
+		plain: func(b *bytes.Buffer, r parquetRow) { plainFloat(b, r.obs.WaterFilmThickness) }},
+	{name: "snow", typ: parquetFloat, converted: -1, present: presentIn(SnowPresent),
+		plain: func(b *bytes.Buffer, r parquetRow) { plainFloat(b, r.obs.SnowThickness) }},
+	{name: "classId", typ: parquetInt32, converted: -1, plain: func(b *bytes.Buffer, r parquetRow) { plainInt32(b, int32(r.obs.Class)) }},
+	{name: "className", typ: parquetByteArray, converted: convertedUTF8, str: func(r parquetRow) string { return r.className }},
+	{name: "scheme", typ: parquetByteArray, converted: convertedUTF8, str: func(r parquetRow) string { return r.scheme }},
+}
+
+type parquetChunk struct {
+	offset     int64
+	dictOffset int64 // 0 => no dictionary
+	dataOffset int64
+	size       int64
+	encodings  []int32
+}
+
+type parquetRowGroup struct {
+	chunks  []parquetChunk
+	numRows int64
+	size    int64
+}
+
+// ParquetWriter writes classified observations as a Parquet file, a row
+// group at a time. Close must be called to write the footer.
+type ParquetWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	scheme  Scheme
+	opts    ParquetOptions
+	rows    []parquetRow
+	groups  []parquetRowGroup
+	numRows int64
+}
+
+func NewParquetWriter(w io.Writer, scheme Scheme, opts ParquetOptions) (*ParquetWriter, error) {
+	if opts.RowGroupRows <= 0 {
+		opts.RowGroupRows = DefaultParquetOptions.RowGroupRows
+	}
+	if opts.PageRows <= 0 {
+		opts.PageRows = DefaultParquetOptions.PageRows
+	}
+	p := &ParquetWriter{w: bufio.NewWriter(w), scheme: scheme, opts: opts}
+	if err := p.write([]byte("PAR1")); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *ParquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+// Write writes obs with the name of its class in the scheme.
+func (p *ParquetWriter) Write(obs ObsRoadweather) error {
+	return p.WriteNamed(obs, p.scheme.Classes[obs.Class])
+}
+
+// WriteNamed writes obs with className, see CSVWriter.WriteNamed.
+func (p *ParquetWriter) WriteNamed(obs ObsRoadweather, className string) error {
+	p.rows = append(p.rows, parquetRow{obs, className, p.scheme.Name})
+	if len(p.rows) >= p.opts.RowGroupRows {
+		return p.flushRowGroup()
+	}
+	return nil
+}
+
+// Close writes the last row group and the footer. It does not close the
+// underlying writer.
+func (p *ParquetWriter) Close() error {
+	if err := p.flushRowGroup(); err != nil {
+		return err
+	}
+	footer := p.fileMetaData()
+	if err := p.write(footer); err != nil {
+		return err
+	}
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(footer)))
+	if err := p.write(size); err != nil {
+		return err
+	}
+	if err := p.write([]byte("PAR1")); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+func (p *ParquetWriter) flushRowGroup() error {
+	if len(p.rows) == 0 {
+		return nil
+	}
+	group := parquetRowGroup{numRows: int64(len(p.rows))}
+	for _, col := range parquetColumns {
+		chunk, err := p.writeChunk(col)
+		if err != nil {
+			return fmt.Errorf("writing Parquet column %s: %v", col.name, err)
+		}
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.size
+	}
+	p.groups = append(p.groups, group)
+	p.numRows += group.numRows
+	p.rows = p.rows[:0]
+	return nil
+}
+
+func (p *ParquetWriter) writePage(header, data []byte) error {
+	if err := p.write(header); err != nil {
+		return err
+	}
+	return p.write(data)
+}
+
+func (p *ParquetWriter) writeChunk(col parquetColumn) (parquetChunk, error) {
+	chunk := parquetChunk{offset: p.offset}
+
+	if col.str == nil {
+		chunk.dataOffset = p.offset
+		chunk.encodings = []int32{encodingPlain, encodingRLE}
+		for start := 0; start < len(p.rows); start += p.opts.PageRows {
+			end := start + p.opts.PageRows
+			if end > len(p.rows) {
+				end = len(p.rows)
+			}
+			var data bytes.Buffer
+			if col.present != nil {
+				// Definition levels, 1 for a value and 0 for null, prefixed
+				// by their length
+				levels := make([]int32, end-start)
+				for i, r := range p.rows[start:end] {
+					if col.present(r) {
+						levels[i] = 1
+					}
+				}
+				var encoded bytes.Buffer
+				writeRLE(&encoded, levels, 1)
+				binary.Write(&data, binary.LittleEndian, uint32(encoded.Len()))
+				data.Write(encoded.Bytes())
+			}
+			for _, r := range p.rows[start:end] {
+				if col.present == nil || col.present(r) {
+					col.plain(&data, r)
+				}
+			}
+			header := dataPageHeader(end-start, encodingPlain, data.Len())
+			if err := p.writePage(header, data.Bytes()); err != nil {
+				return chunk, err
+			}
+		}
+		chunk.size = p.offset - chunk.offset
+		return chunk, nil
+	}
+
+	dict := make(map[string]int32)
+	values := []string{}
+	indices := make([]int32, len(p.rows))
+	for i, r := range p.rows {
+		s := col.str(r)
+		idx, ok := dict[s]
+		if !ok {
+			idx = int32(len(values))
+			dict[s] = idx
+			values = append(values, s)
+		}
+		indices[i] = idx
+	}
+
+	var data bytes.Buffer
+	for _, s := range values {
+		plainInt32(&data, int32(len(s)))
+		data.WriteString(s)
+	}
+	chunk.dictOffset = p.offset
+	chunk.encodings = []int32{encodingPlainDictionary, encodingRLE}
+	if err := p.writePage(dictPageHeader(len(values), data.Len()), data.Bytes()); err != nil {
+		return chunk, err
+	}
+
+	width := bits.Len32(uint32(len(values) - 1))
+	if width == 0 {
+		width = 1
+	}
+	chunk.dataOffset = p.offset
+	for start := 0; start < len(indices); start += p.opts.PageRows {
+		end := start + p.opts.PageRows
+		if end > len(indices) {
+			end = len(indices)
+		}
+		data.Reset()
+		data.WriteByte(byte(width))
+		writeRLE(&data, indices[start:end], width)
+		header := dataPageHeader(end-start, encodingPlainDictionary, data.Len())
+		if err := p.writePage(header, data.Bytes()); err != nil {
+			return chunk, err
+		}
+	}
+	chunk.size = p.offset - chunk.offset
+	return chunk, nil
+}
+
+// writeRLE writes values as RLE runs of the RLE/bit-packing hybrid encoding.
+func writeRLE(b *bytes.Buffer, values []int32, width int) {
+	bytesPerValue := (width + 7) / 8
+	v := make([]byte, 4)
+	for i := 0; i < len(values); {
+		j := i + 1
+		for j < len(values) && values[j] == values[i] {
+			j++
+		}
+		b.Write(binary.AppendUvarint(nil, uint64(j-i)<<1))
+		binary.LittleEndian.PutUint32(v, uint32(values[i]))
+		b.Write(v[:bytesPerValue])
+		i = j
+	}
+}
+
+func dataPageHeader(numValues int, encoding int32, size int) []byte {
+	t := newThriftWriter()
+	t.i32(1, pageData)
+	t.i32(2, int32(size))
+	t.i32(3, int32(size))
+	t.structField(5, func() {
+		t.i32(1, int32(numValues))
+		t.i32(2, encoding)
+		t.i32(3, encodingRLE)
+		t.i32(4, encodingRLE)
+	})
+	return t.bytes()
+}
+
+func dictPageHeader(numValues int, size int) []byte {
+	t := newThriftWriter()
+	t.i32(1, pageDictionary)
+	t.i32(2, int32(size))
+	t.i32(3, int32(size))
+	t.structField(7, func() {
+		t.i32(1, int32(numValues))
+		t.i32(2, encodingPlainDictionary)
+	})
+	return t.bytes()
+}
+
+func (p *ParquetWriter) fileMetaData() []byte {
+	t := newThriftWriter()
+	t.i32(1, 1)
+	t.structList(2, len(parquetColumns)+1, func(i int) {
+		if i == 0 {
+			t.str(4, "schema")
+			t.i32(5, int32(len(parquetColumns)))
+			return
+		}
+		col := parquetColumns[i-1]
+		t.i32(1, col.typ)
+		if col.present != nil {
+			t.i32(3, parquetOptional)
+		} else {
+			t.i32(3, parquetRequired)
+		}
+		t.str(4, col.name)
+		if col.converted >= 0 {
+			t.i32(6, col.converted)
+		}
+		if col.timestamp {
+			t.structField(10, func() {
+				t.structField(8, func() {
+					t.boolean(1, true)
+					t.structField(2, func() {
+						t.structField(1, func() {})
+					})
+				})
+			})
+		} else if col.converted == convertedUTF8 {
+			t.structField(10, func() {
+				t.structField(1, func() {})
+			})
+		}
+	})
+	t.i64(3, p.numRows)
+	t.structList(4, len(p.groups), func(i int) {
+		group := p.groups[i]
+		t.structList(1, len(group.chunks), func(c int) {
+			chunk := group.chunks[c]
+			col := parquetColumns[c]
+			t.i64(2, chunk.offset)
+			t.structField(3, func() {
+				t.i32(1, col.typ)
+				t.i32List(2, chunk.encodings)
+				t.strList(3, []string{col.name})
+				t.i32(4, 0) // UNCOMPRESSED
+				t.i64(5, group.numRows)
+				t.i64(6, chunk.size)
+				t.i64(7, chunk.size)
+				t.i64(9, chunk.dataOffset)
+				if chunk.dictOffset > 0 {
+					t.i64(11, chunk.dictOffset)
+				}
+			})
+		})
+		t.i64(2, group.size)
+		t.i64(3, group.numRows)
+	})
+	t.structList(5, 2, func(i int) {
+		kv := [][2]string{{"frostclient.scheme", p.scheme.Name}, {"frostclient.version", Version}}[i]
+		t.str(1, kv[0])
+		t.str(2, kv[1])
+	})
+	t.str(6, "frostclient-roadweather version "+Version)
+	return t.bytes()
+}
+
+// thriftWriter writes a struct in the Thrift compact protocol.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // the last field id of each open struct
+}
+
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+func (t *thriftWriter) bytes() []byte {
+	t.buf.WriteByte(0) // stop
+	return t.buf.Bytes()
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) str(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) boolean(id int16, v bool) {
+	if v {
+		t.field(id, thriftTrue)
+	} else {
+		t.field(id, thriftFalse)
+	}
+}
+
+func (t *thriftWriter) listHeader(n int, typ byte) {
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | typ)
+		return
+	}
+	t.buf.WriteByte(0xf0 | typ)
+	t.varint(uint64(n))
+}
+
+func (t *thriftWriter) structBody(fields func()) {
+	t.last = append(t.last, 0)
+	fields()
+	t.buf.WriteByte(0) // stop
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) structField(id int16, fields func()) {
+	t.field(id, thriftStruct)
+	t.structBody(fields)
+}
+
+func (t *thriftWriter) structList(id int16, n int, elem func(i int)) {
+	t.field(id, thriftList)
+	t.listHeader(n, thriftStruct)
+	for i := 0; i < n; i++ {
+		t.structBody(func() { elem(i) })
+	}
+}
+
+func (t *thriftWriter) i32List(id int16, vs []int32) {
+	t.field(id, thriftList)
+	t.listHeader(len(vs), thriftI32)
+	for _, v := range vs {
+		t.zigzag(int64(v))
+	}
+}
+
+func (t *thriftWriter) strList(id int16, vs []string) {
+	t.field(id, thriftList)
+	t.listHeader(len(vs), thriftBinary)
+	for _, s := range vs {
+		t.varint(uint64(len(s)))
+		t.buf.WriteString(s)
+	}
+}
+
+// WriteParquetMonths writes class2Obses to dir partitioned by month, as
+// dir/month=2023-02/part-0.parquet, so Spark and pyarrow read the month as a
+// column. The rows are sorted by station and time, which keeps the dictionary
+// encoded columns small. It returns the files written.
+func WriteParquetMonths(dir string, scheme Scheme, class2Obses map[int][]ObsRoadweather, opts ParquetOptions) ([]string, error) {
+	months := make(map[string][]ObsRoadweather)
+	for _, obses := range class2Obses {
+		for _, obs := range obses {
+			m := obs.RefTime.UTC().Format("2006-01")
+			months[m] = append(months[m], obs)
+		}
+	}
+	keys := make([]string, 0, len(months))
+	for m := range months {
+		keys = append(keys, m)
+	}
+	sort.Strings(keys)
+
+	files := []string{}
+	for _, m := range keys {
+		obses := months[m]
+		sort.SliceStable(obses, func(i, j int) bool {
+			if obses[i].Station != obses[j].Station {
+				return obses[i].Station < obses[j].Station
+			}
+			if obses[i].CamID != obses[j].CamID {
+				return obses[i].CamID < obses[j].CamID
+			}
+			return obses[i].RefTime.Before(obses[j].RefTime)
+		})
+
+		path := filepath.Join(dir, "month="+m, "part-0.parquet")
+		if err := writeParquetFile(path, scheme, obses, opts); err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+func writeParquetFile(path string, scheme Scheme, obses []ObsRoadweather, opts ParquetOptions) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("os.MkdirAll(%s): %v", filepath.Dir(path), err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("os.Create(%s): %v", path, err)
+	}
+	pw, err := NewParquetWriter(f, scheme, opts)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("NewParquetWriter(%s): %v", path, err)
+	}
+	for _, obs := range obses {
+		if err := pw.Write(obs); err != nil {
+			f.Close()
+			return fmt.Errorf("writing %s: %v", path, err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %v", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,164 @@
+package frostclient
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// QualityMissing is the QualityCode of observations without one.
+const QualityMissing = -1
+
+// QualityCodes maps Frost quality codes to their meaning. LoadQualityCodes
+// replaces it with the descriptions from Frost, which NewQualityFilter does
+// the first time it is called.
+var QualityCodes = map[int]string{
+	0: "OK",
+}
+
+var loadQualityCodesOnce sync.Once
+
+// QualityMeaning returns the meaning of a quality code.
+func QualityMeaning(code int) string {
+	if code == QualityMissing {
+		return "No quality code"
+	}
+	if m, ok := QualityCodes[code]; ok {
+		return m
+	}
+	return fmt.Sprintf("Flagged by quality control (code %d)", code)
+}
+
+func (o Observation) Quality() string {
+	return QualityMeaning(o.QualityCode)
+}
+
+func (s Sensor) Quality() string {
+	return QualityMeaning(s.QualityCode)
+}
+
+type qualityCode struct {
+	Value       int    `json:"value"`
+	Description string `json:"description"`
+}
+
+// LoadQualityCodes fetches the quality code descriptions from Frost into
+// QualityCodes.
+func LoadQualityCodes() error {
+	url := "https://frost.met.no/observations/availableQualityCodes/v0.jsonld?lang=en-US"
+	resp, err := httpReq(url)
+	if err != nil {
+		return fmt.Errorf("http.Get(%s) failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("http.Get(%s) Unexpected response code %d", url, resp.StatusCode)
+	}
+
+	res, drift, err := DecodeResponse[qualityCode](resp.Body)
+	if err != nil {
+		return err
+	}
+	logDrift(url, drift)
+
+	codes := make(map[int]string, len(res.Data))
+	for _, c := range res.Data {
+		codes[c.Value] = c.Description
+	}
+	QualityCodes = codes
+	return nil
+}
+
+// QualityPolicy decides which observations to use. A code must be in Accept
+// when Accept is set, and not above MaxQualityCode unless that is negative.
+type QualityPolicy struct {
+	Accept         []int
+	MaxQualityCode int
+	AcceptMissing  bool
+}
+
+// DefaultQualityPolicy rejects the values Frost marks as suspicious or
+// failed, quality codes 3 and up. Values without a quality code are accepted
+// since not all road weather timeseries have one.
+var DefaultQualityPolicy = QualityPolicy{
+	MaxQualityCode: 2,
+	AcceptMissing:  true,
+}
+
+func (p QualityPolicy) Accepts(code int) bool {
+	if code == QualityMissing {
+		return p.AcceptMissing
+	}
+	if len(p.Accept) > 0 {
+		found := false
+		for _, c := range p.Accept {
+			if c == code {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return p.MaxQualityCode < 0 || code <= p.MaxQualityCode
+}
+
+// QualityReport counts accepted and rejected values per element and quality
+// code.
+type QualityReport struct {
+	Accepted int
+	Rejected map[string]int // "element code" => values
+}
+
+func (r QualityReport) String() string {
+	var b strings.Builder
+	rejected := 0
+	keys := make([]string, 0, len(r.Rejected))
+	for k, n := range r.Rejected {
+		keys = append(keys, k)
+		rejected += n
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(&b, "Quality: accepted %d, rejected %d\n", r.Accepted, rejected)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "  %s: %d\n", k, r.Rejected[k])
+	}
+	return b.String()
+}
+
+// QualityFilter applies Policy to observations and records the outcome in
+// Report.
+type QualityFilter struct {
+	Policy QualityPolicy
+	Report QualityReport
+}
+
+func NewQualityFilter(policy QualityPolicy) *QualityFilter {
+	loadQualityCodesOnce.Do(func() {
+		if err := LoadQualityCodes(); err != nil {
+			log.Printf("LoadQualityCodes: %v", err)
+		}
+	})
+	return &QualityFilter{
+		Policy: policy,
+		Report: QualityReport{Rejected: make(map[string]int)},
+	}
+}
+
+// Filter returns the observations accepted by the policy.
+func (f *QualityFilter) Filter(obses []Observation) []Observation {
+	accepted := make([]Observation, 0, len(obses))
+	for _, o := range obses {
+		if !f.Policy.Accepts(o.QualityCode) {
+			f.Report.Rejected[fmt.Sprintf("%s %d (%s)", o.ElementID, o.QualityCode, o.Quality())]++
+			continue
+		}
+		f.Report.Accepted++
+		accepted = append(accepted, o)
+	}
+	return accepted
+}
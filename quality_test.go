@@ -0,0 +1,70 @@
+package frostclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQualityPolicyAccepts(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy QualityPolicy
+		code   int
+		want   bool
+	}{
+		{"default OK", DefaultQualityPolicy, 0, true},
+		{"default corrected", DefaultQualityPolicy, 2, true},
+		{"default suspicious", DefaultQualityPolicy, 3, false},
+		{"default failed", DefaultQualityPolicy, 7, false},
+		{"default missing", DefaultQualityPolicy, QualityMissing, true},
+		{"missing refused", QualityPolicy{MaxQualityCode: 2}, QualityMissing, false},
+		{"no maximum", QualityPolicy{MaxQualityCode: -1}, 9, true},
+		{"in accept list", QualityPolicy{Accept: []int{0, 4}, MaxQualityCode: -1}, 4, true},
+		{"not in accept list", QualityPolicy{Accept: []int{0, 4}, MaxQualityCode: -1}, 1, false},
+		{"in accept list above maximum", QualityPolicy{Accept: []int{0, 4}, MaxQualityCode: 2}, 4, false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Accepts(tt.code); got != tt.want {
+			t.Errorf("%s: Accepts(%d) = %v, want %v", tt.name, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestQualityFilter(t *testing.T) {
+	f := &QualityFilter{Policy: DefaultQualityPolicy, Report: QualityReport{Rejected: map[string]int{}}}
+	obses := []Observation{
+		{ElementID: "road_ice_thickness", QualityCode: 0},
+		{ElementID: "road_ice_thickness", QualityCode: 3},
+		{ElementID: "road_snow_thickness", QualityCode: QualityMissing},
+		{ElementID: "road_snow_thickness", QualityCode: 3},
+		{ElementID: "road_ice_thickness", QualityCode: 3},
+	}
+	got := f.Filter(obses)
+	if want := []Observation{obses[0], obses[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter = %+v, want %+v", got, want)
+	}
+	if f.Report.Accepted != 2 || len(f.Report.Rejected) != 2 {
+		t.Errorf("report = %+v, want 2 accepted and 2 kinds rejected", f.Report)
+	}
+	s := f.Report.String()
+	if !strings.Contains(s, "accepted 2, rejected 3") || !strings.Contains(s, "road_ice_thickness 3 (") {
+		t.Errorf("report string:\n%s", s)
+	}
+}
+
+func TestQualityMeaning(t *testing.T) {
+	tests := []struct {
+		code int
+		want string
+	}{
+		{QualityMissing, "No quality code"},
+		{0, QualityCodes[0]},
+		{99, "Flagged by quality control (code 99)"},
+	}
+	for _, tt := range tests {
+		if got := QualityMeaning(tt.code); got != tt.want {
+			t.Errorf("QualityMeaning(%d) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package frostclient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ObsQuery holds the parameters of an /observations/v0 request. Empty fields
+// are left out of the request, which Frost treats as "all".
+type ObsQuery struct {
+	Sources               []string `json:"sources,omitempty"`
+	Elements              []string `json:"elements,omitempty"`
+	ReferenceTime         string   `json:"referenceTime,omitempty"` // ISO-8601 interval, e.g. 2023-02-10T00:00Z/2023-02-11T00:00Z
+	TimeOffsets           []string `json:"timeOffsets,omitempty"`
+	TimeResolutions       []string `json:"timeResolutions,omitempty"`
+	TimeSeriesIDs         []int    `json:"timeSeriesIds,omitempty"`
+	PerformanceCategories []string `json:"performanceCategories,omitempty"`
+	ExposureCategories    []string `json:"exposureCategories,omitempty"`
+}
+
+// DefaultObsQuery is the query the GetDataFromFrost functions start from,
+// filling in Sources and ReferenceTime. All timeseries are requested and
+// merged by DefaultSensorPriority.
+var DefaultObsQuery = ObsQuery{
+	Elements:        RoadweatherElements,
+	TimeOffsets:     []string{"PT0H"},
+	TimeResolutions: []string{"PT10M"},
+}
+
+// URL returns the request URL for q.
+func (q ObsQuery) URL() string {
+	params := []string{}
+	add := func(name string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		params = append(params, name+"="+strings.Join(values, ","))
+	}
+
+	ids := make([]string, len(q.TimeSeriesIDs))
+	for i, id := range q.TimeSeriesIDs {
+		ids[i] = fmt.Sprintf("%d", id)
+	}
+
+	add("sources", q.Sources)
+	if q.ReferenceTime != "" {
+		params = append(params, "referencetime="+q.ReferenceTime)
+	}
+	add("elements", q.Elements)
+	add("timeoffsets", q.TimeOffsets)
+	add("timeresolutions", q.TimeResolutions)
+	add("timeseriesids", ids)
+	add("performancecategories", q.PerformanceCategories)
+	add("exposurecategories", q.ExposureCategories)
+
+	return "https://frost.met.no/observations/v0.jsonld?" + strings.Join(params, "&")
+}
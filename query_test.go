@@ -0,0 +1,35 @@
+package frostclient
+
+import "testing"
+
+func TestObsQueryURL(t *testing.T) {
+	const base = "https://frost.met.no/observations/v0.jsonld?"
+	tests := []struct {
+		name string
+		q    ObsQuery
+		want string
+	}{
+		{"empty", ObsQuery{}, base},
+		{
+			name: "default",
+			q:    ObsQuery{Sources: []string{"SN1:0", "SN2:0"}, ReferenceTime: "2023-02-10T00:00Z/2023-02-11T00:00Z", Elements: DefaultObsQuery.Elements, TimeOffsets: DefaultObsQuery.TimeOffsets, TimeResolutions: DefaultObsQuery.TimeResolutions},
+			want: base + "sources=SN1:0,SN2:0&referencetime=2023-02-10T00:00Z/2023-02-11T00:00Z" +
+				"&elements=road_ice_thickness,road_water_film_thickness,road_snow_thickness&timeoffsets=PT0H&timeresolutions=PT10M",
+		},
+		{
+			name: "all parameters",
+			q: ObsQuery{
+				Elements:              []string{"road_ice_thickness"},
+				TimeSeriesIDs:         []int{0, 1},
+				PerformanceCategories: []string{"A", "B"},
+				ExposureCategories:    []string{"1"},
+			},
+			want: base + "elements=road_ice_thickness&timeseriesids=0,1&performancecategories=A,B&exposurecategories=1",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.q.URL(); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package refcheck reads the files written by frostclient back with
+// reference implementations of their formats. It is a module of its own so
+// frostclient does not depend on them.
+package refcheck
@@ -0,0 +1,166 @@
+package refcheck
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	frostclient "github.com/metno/frostclient-roadweather"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+// parquetTestRow is a row as read back by the reference reader.
+type parquetTestRow struct {
+	RefTime   int64    `parquet:"name=refTime, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Station   string   `parquet:"name=station, type=BYTE_ARRAY, convertedtype=UTF8"`
+	FrostID   string   `parquet:"name=frostID, type=BYTE_ARRAY, convertedtype=UTF8"`
+	CamID     int32    `parquet:"name=camID, type=INT32"`
+	Ice       *float32 `parquet:"name=ice, type=FLOAT, repetitiontype=OPTIONAL"`
+	Water     *float32 `parquet:"name=water, type=FLOAT, repetitiontype=OPTIONAL"`
+	Snow      *float32 `parquet:"name=snow, type=FLOAT, repetitiontype=OPTIONAL"`
+	ClassID   int32    `parquet:"name=classId, type=INT32"`
+	ClassName string   `parquet:"name=className, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Scheme    string   `parquet:"name=scheme, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+func readParquet(t *testing.T, path string) []parquetTestRow {
+	t.Helper()
+	fr, err := local.NewLocalFileReader(path)
+	if err != nil {
+		t.Fatalf("NewLocalFileReader: %v", err)
+	}
+	defer fr.Close()
+	pr, err := reader.NewParquetReader(fr, new(parquetTestRow), 1)
+	if err != nil {
+		t.Fatalf("NewParquetReader: %v", err)
+	}
+	defer pr.ReadStop()
+	rows := make([]parquetTestRow, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return rows
+}
+
+func float32p(v float32) *float32 {
+	return &v
+}
+
+func TestParquetReadBack(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	obses := []frostclient.ObsRoadweather{}
+	want := []parquetTestRow{}
+	stations := []string{"SN12345:0", "SN67890:0", "SN11111:1"}
+	for i := 0; i < 250; i++ {
+		obs := frostclient.ObsRoadweather{
+			RefTime:            start.Add(time.Duration(i) * 10 * time.Minute),
+			Station:            stations[i/100],
+			FrostID:            stations[i/100],
+			CamID:              100 + i/100,
+			IceThickness:       float32(i%4) / 4,
+			WaterFilmThickness: float32(i%3) / 10,
+			SnowThickness:      float32(i%5) / 2,
+			Present:            frostclient.AllPresent,
+		}
+		// Missing elements in runs and alone, to cover both level encodings
+		if i >= 20 && i < 60 {
+			obs.Present &^= frostclient.IcePresent
+		}
+		if i%7 == 0 {
+			obs.Present &^= frostclient.SnowPresent
+		}
+		if i == 249 {
+			obs.Present = 0
+		}
+		obs.Class = frostclient.Scheme8.ClassifyPartial(obs)
+		obses = append(obses, obs)
+
+		row := parquetTestRow{
+			RefTime:   obs.RefTime.UnixMilli(),
+			Station:   obs.Station,
+			FrostID:   obs.FrostID,
+			CamID:     int32(obs.CamID),
+			ClassID:   int32(obs.Class),
+			ClassName: frostclient.Scheme8.Classes[obs.Class],
+			Scheme:    frostclient.Scheme8.Name,
+		}
+		if obs.Present&frostclient.IcePresent != 0 {
+			row.Ice = float32p(obs.IceThickness)
+		}
+		if obs.Present&frostclient.WaterFilmPresent != 0 {
+			row.Water = float32p(obs.WaterFilmThickness)
+		}
+		if obs.Present&frostclient.SnowPresent != 0 {
+			row.Snow = float32p(obs.SnowThickness)
+		}
+		want = append(want, row)
+	}
+
+	// Several row groups and pages per chunk
+	path := filepath.Join(t.TempDir(), "obs.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pw, err := frostclient.NewParquetWriter(f, frostclient.Scheme8, frostclient.ParquetOptions{RowGroupRows: 100, PageRows: 30})
+	if err != nil {
+		t.Fatalf("NewParquetWriter: %v", err)
+	}
+	for _, obs := range obses {
+		if err := pw.Write(obs); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readParquet(t, path)
+	if len(got) != len(want) {
+		t.Fatalf("read %d rows, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("row %d\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParquetMonths(t *testing.T) {
+	class2Obses := map[int][]frostclient.ObsRoadweather{
+		frostclient.Dry: {
+			{RefTime: time.Date(2023, 2, 28, 23, 50, 0, 0, time.UTC), Station: "SN1:0", Present: frostclient.AllPresent},
+			{RefTime: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Station: "SN1:0", Present: frostclient.AllPresent},
+		},
+		frostclient.Wet: {
+			{RefTime: time.Date(2023, 3, 1, 0, 10, 0, 0, time.UTC), Station: "SN1:0", WaterFilmThickness: 0.2, Class: frostclient.Wet, Present: frostclient.WaterFilmPresent},
+		},
+	}
+	dir := t.TempDir()
+	files, err := frostclient.WriteParquetMonths(dir, frostclient.Scheme3, class2Obses, frostclient.DefaultParquetOptions)
+	if err != nil {
+		t.Fatalf("WriteParquetMonths: %v", err)
+	}
+	wantFiles := []string{
+		filepath.Join(dir, "month=2023-02", "part-0.parquet"),
+		filepath.Join(dir, "month=2023-03", "part-0.parquet"),
+	}
+	if !reflect.DeepEqual(files, wantFiles) {
+		t.Fatalf("files = %v, want %v", files, wantFiles)
+	}
+
+	march := readParquet(t, files[1])
+	if len(march) != 2 {
+		t.Fatalf("March has %d rows, want 2", len(march))
+	}
+	wet := march[1]
+	if wet.ClassName != "Wet" || wet.Water == nil || *wet.Water != 0.2 || wet.Ice != nil || wet.Snow != nil {
+		t.Errorf("Wet row = %+v, want water 0.2 and no ice or snow", wet)
+	}
+}
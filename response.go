@@ -0,0 +1,217 @@
+package frostclient
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Response is the JSON-LD envelope of all Frost responses.
+type Response[T any] struct {
+	Context          string    `json:"@context"`
+	Type             string    `json:"@type"`
+	APIVersion       string    `json:"apiVersion"`
+	License          string    `json:"license"`
+	CreatedAt        time.Time `json:"createdAt"`
+	QueryTime        float64   `json:"queryTime"`
+	CurrentItemCount int       `json:"currentItemCount"`
+	ItemsPerPage     int       `json:"itemsPerPage"`
+	Offset           int       `json:"offset"`
+	TotalItemCount   int       `json:"totalItemCount"`
+	NextLink         string    `json:"nextLink,omitempty"`
+	PreviousLink     string    `json:"previousLink,omitempty"`
+	CurrentLink      string    `json:"currentLink"`
+	Data             []T       `json:"data"`
+}
+
+type Geometry struct {
+	Type        string    `json:"@type"`
+	Coordinates []float64 `json:"coordinates"` // lon, lat
+	Nearest     bool      `json:"nearest"`
+}
+
+// Source is an item from /sources/v0.
+type Source struct {
+	Type           string    `json:"@type"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	ShortName      string    `json:"shortName"`
+	Country        string    `json:"country"`
+	CountryCode    string    `json:"countryCode"`
+	Geometry       Geometry  `json:"geometry"`
+	Masl           int       `json:"masl"`
+	ValidFrom      time.Time `json:"validFrom"`
+	ValidTo        time.Time `json:"validTo"`
+	County         string    `json:"county"`
+	CountyID       int       `json:"countyId"`
+	Municipality   string    `json:"municipality"`
+	MunicipalityID int       `json:"municipalityId"`
+	StationHolders []string  `json:"stationHolders"`
+	ExternalIds    []string  `json:"externalIds"`
+	WigosID        string    `json:"wigosId"`
+}
+
+// TimeSeries is an item from /observations/availableTimeSeries/v0.
+type TimeSeries struct {
+	SourceID            string    `json:"sourceId"`
+	ValidFrom           time.Time `json:"validFrom"`
+	ValidTo             time.Time `json:"validTo"`
+	TimeOffset          string    `json:"timeOffset"`
+	TimeResolution      string    `json:"timeResolution"`
+	TimeSeriesID        int       `json:"timeSeriesId"`
+	ElementID           string    `json:"elementId"`
+	Unit                string    `json:"unit"`
+	PerformanceCategory string    `json:"performanceCategory"`
+	ExposureCategory    string    `json:"exposureCategory"`
+	Status              string    `json:"status"`
+	URI                 string    `json:"uri"`
+}
+
+// ObservationsAtRefTime is an item from /observations/v0: the observations of
+// a source at one reference time.
+type ObservationsAtRefTime struct {
+	SourceID      string        `json:"sourceId"`
+	ReferenceTime time.Time     `json:"referenceTime"`
+	Observations  []Observation `json:"observations"`
+}
+
+type Observation struct {
+	ElementID           string  `json:"elementId"`
+	Value               float32 `json:"value"`
+	Unit                string  `json:"unit"`
+	TimeOffset          string  `json:"timeOffset"`
+	TimeResolution      string  `json:"timeResolution"`
+	TimeSeriesID        int     `json:"timeSeriesId"`
+	PerformanceCategory string  `json:"performanceCategory"`
+	ExposureCategory    string  `json:"exposureCategory"`
+	QualityCode         int     `json:"qualityCode"` // QualityMissing when not in the response
+}
+
+// UnmarshalJSON sets QualityCode to QualityMissing when the response has none.
+func (o *Observation) UnmarshalJSON(b []byte) error {
+	type plain Observation
+	p := plain{QualityCode: QualityMissing}
+	err := json.Unmarshal(b, &p)
+	if err != nil {
+		return err
+	}
+	*o = Observation(p)
+	return nil
+}
+
+type StationHolderReq = Response[Source]
+type ObsType = Response[TimeSeries]
+type ObsReq = Response[ObservationsAtRefTime]
+
+// Deprecated: Station was an observation response, use ObsReq.
+type Station = Response[ObservationsAtRefTime]
+
+// SchemaDrift lists fields in a response that the types do not know about,
+// as paths like "data[].observations[].level".
+type SchemaDrift struct {
+	Unknown []string
+}
+
+func (d SchemaDrift) Empty() bool {
+	return len(d.Unknown) == 0
+}
+
+func (d SchemaDrift) String() string {
+	return "unknown fields: " + strings.Join(d.Unknown, ", ")
+}
+
+// DecodeResponse decodes a Frost response, ignoring unknown fields but listing
+// them in the returned SchemaDrift.
+func DecodeResponse[T any](r io.Reader) (Response[T], SchemaDrift, error) {
+	res := Response[T]{}
+	drift := SchemaDrift{}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return res, drift, err
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return res, drift, err
+	}
+
+	var raw interface{}
+	err = json.Unmarshal(body, &raw)
+	if err != nil {
+		return res, drift, err
+	}
+	unknown := make(map[string]bool)
+	findUnknown(raw, reflect.TypeOf(res), "", unknown)
+	for path := range unknown {
+		drift.Unknown = append(drift.Unknown, path)
+	}
+	sort.Strings(drift.Unknown)
+
+	return res, drift, nil
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	timeType        = reflect.TypeOf(time.Time{})
+)
+
+func findUnknown(raw interface{}, t reflect.Type, path string, unknown map[string]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType || (t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(unmarshalerType)) {
+		return
+	}
+
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "" {
+				name = f.Name
+			}
+			fields[strings.ToLower(name)] = f.Type
+		}
+		for k, val := range v {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			ft, ok := fields[strings.ToLower(k)]
+			if !ok {
+				unknown[p] = true
+				continue
+			}
+			findUnknown(val, ft, p, unknown)
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for _, val := range v {
+			findUnknown(val, t.Elem(), path+"[]", unknown)
+		}
+	}
+}
+
+var reportedDrift = make(map[string]bool)
+
+// logDrift logs each unknown field once.
+func logDrift(url string, drift SchemaDrift) {
+	for _, path := range drift.Unknown {
+		if reportedDrift[path] {
+			continue
+		}
+		reportedDrift[path] = true
+		log.Printf("Schema drift in %s: unknown field %s", url, path)
+	}
+}
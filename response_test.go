@@ -0,0 +1,81 @@
+package frostclient
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeResponseDrift(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "known fields",
+			body: `{"@context": "https://frost.met.no/schema", "apiVersion": "v0", "data": [
+				{"sourceId": "SN1:0", "referenceTime": "2023-02-10T00:00:00.000Z", "observations": [
+					{"elementId": "road_ice_thickness", "value": 0.1, "unit": "mm", "qualityCode": 0}]}]}`,
+			want: nil,
+		},
+		{
+			name: "unknown envelope and nested fields",
+			body: `{"apiVersion": "v0", "warnings": [], "data": [
+				{"sourceId": "SN1:0", "referenceTime": "2023-02-10T00:00:00Z", "observations": [
+					{"elementId": "road_ice_thickness", "value": 0.1, "level": {"value": 0}},
+					{"elementId": "road_snow_thickness", "value": 0, "level": {"value": 0}, "flag": 1}]}]}`,
+			want: []string{"data[].observations[].flag", "data[].observations[].level", "warnings"},
+		},
+		{
+			name: "field names in another case",
+			body: `{"APIVERSION": "v0", "data": [{"SourceID": "SN1:0"}]}`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		_, drift, err := DecodeResponse[ObservationsAtRefTime](strings.NewReader(tt.body))
+		if err != nil {
+			t.Errorf("%s: DecodeResponse: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(drift.Unknown, tt.want) {
+			t.Errorf("%s: unknown = %v, want %v", tt.name, drift.Unknown, tt.want)
+		}
+		if drift.Empty() != (len(tt.want) == 0) {
+			t.Errorf("%s: Empty() = %v", tt.name, drift.Empty())
+		}
+	}
+}
+
+func TestDecodeResponseObservations(t *testing.T) {
+	body := `{"apiVersion": "v0", "totalItemCount": 1, "data": [
+		{"sourceId": "SN1:0", "referenceTime": "2023-02-10T12:00:00.000Z", "observations": [
+			{"elementId": "road_ice_thickness", "value": 0.25, "unit": "mm", "timeSeriesId": 1, "qualityCode": 2},
+			{"elementId": "road_snow_thickness", "value": 0, "unit": "mm"}]}]}`
+	res, _, err := DecodeResponse[ObservationsAtRefTime](strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("DecodeResponse: %v", err)
+	}
+	if res.APIVersion != "v0" || res.TotalItemCount != 1 || len(res.Data) != 1 {
+		t.Fatalf("envelope = %+v", res)
+	}
+	at := res.Data[0]
+	if !at.ReferenceTime.Equal(time.Date(2023, 2, 10, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("reference time = %s", at.ReferenceTime)
+	}
+	want := []Observation{
+		{ElementID: "road_ice_thickness", Value: 0.25, Unit: "mm", TimeSeriesID: 1, QualityCode: 2},
+		{ElementID: "road_snow_thickness", Value: 0, Unit: "mm", QualityCode: QualityMissing},
+	}
+	if !reflect.DeepEqual(at.Observations, want) {
+		t.Errorf("observations = %+v, want %+v", at.Observations, want)
+	}
+}
+
+func TestDecodeResponseInvalid(t *testing.T) {
+	if _, _, err := DecodeResponse[Source](strings.NewReader(`{"data": [`)); err == nil {
+		t.Errorf("DecodeResponse of truncated JSON succeeded")
+	}
+}
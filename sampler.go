@@ -0,0 +1,288 @@
+package frostclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+)
+
+// Sampler selects the observations to keep from a batch of classified
+// observations. The GetDataFromFrost functions sample each day as it is
+// fetched, or the whole series at once when Build.Smooth or
+// Build.SeriesQuality is set.
+type Sampler interface {
+	Sample(obses []ObsRoadweather) []ObsRoadweather
+}
+
+// SamplerFunc keeps the observations it returns true for.
+type SamplerFunc func(obs ObsRoadweather) bool
+
+func (f SamplerFunc) Sample(obses []ObsRoadweather) []ObsRoadweather {
+	kept := []ObsRoadweather{}
+	for _, obs := range obses {
+		if f(obs) {
+			kept = append(kept, obs)
+		}
+	}
+	return kept
+}
+
+// described gives a sampler made from a function the description and seed
+// recorded in dataset snapshots.
+type described struct {
+	Sampler
+	desc string
+	seed int64
+}
+
+func (d described) String() string {
+	return d.desc
+}
+
+// describeSampler returns the description of s recorded in snapshots, its
+// String method if it has one.
+func describeSampler(s Sampler) string {
+	if s == nil {
+		return "All"
+	}
+	if st, ok := s.(fmt.Stringer); ok {
+		return st.String()
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// samplerDescribed reports whether s and the samplers it is made of all
+// have descriptions that tell them apart, so a snapshot can be rebuilt with
+// them. A Daylight with a custom Locate and samplers without a String method
+// must be named with Named.
+func samplerDescribed(s Sampler) bool {
+	switch s := s.(type) {
+	case nil, described:
+		return true
+	case Daylight:
+		return s.Locate == nil
+	case perClass:
+		for _, c := range s.samplers {
+			if !samplerDescribed(c) {
+				return false
+			}
+		}
+		return samplerDescribed(s.def)
+	case chain:
+		for _, c := range s {
+			if !samplerDescribed(c) {
+				return false
+			}
+		}
+		return true
+	}
+	_, ok := s.(fmt.Stringer)
+	return ok
+}
+
+// samplerSeed returns the first seed used by s, 0 if it is not random.
+func samplerSeed(s Sampler) int64 {
+	switch s := s.(type) {
+	case described:
+		return s.seed
+	case RandomPerDay:
+		return s.Seed
+	case perClass:
+		classes := maps.Keys(s.samplers)
+		sort.Ints(classes)
+		for _, c := range classes {
+			if seed := samplerSeed(s.samplers[c]); seed != 0 {
+				return seed
+			}
+		}
+		return samplerSeed(s.def)
+	case chain:
+		for _, c := range s {
+			if seed := samplerSeed(c); seed != 0 {
+				return seed
+			}
+		}
+	}
+	return 0
+}
+
+// Named describes s by name in dataset snapshots, for samplers made from a
+// function or otherwise without a description of their own.
+func Named(name string, s Sampler) Sampler {
+	return described{s, name, samplerSeed(s)}
+}
+
+// All keeps every observation.
+var All Sampler = described{SamplerFunc(func(obs ObsRoadweather) bool { return true }), "All", 0}
+
+// EveryNMinutes keeps the observations at multiples of n minutes after
+// midnight UTC. EveryNMinutes(60) keeps the whole hours. n must be positive.
+func EveryNMinutes(n int) (Sampler, error) {
+	return EveryNMinutesIn(time.UTC, n)
+}
+
+// FixedHours keeps the observations at minute 0 of hours (UTC). Most of
+// these are dark in the Norwegian winter, see FixedHoursIn and Daylight.
+func FixedHours(hours ...int) Sampler {
+	return FixedHoursIn(time.UTC, hours...)
+}
+
+// seededHash gives a reproducible pseudo random number for seed and the
+// values, independent of the order observations are sampled in.
+func seededHash(seed int64, s string, t time.Time) uint64 {
+	h := fnv.New64a()
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(seed))
+	h.Write(b)
+	h.Write([]byte(s))
+	binary.LittleEndian.PutUint64(b, uint64(t.UnixNano()))
+	h.Write(b)
+	return h.Sum64()
+}
+
+// RandomPerDay keeps K random observations per station and UTC day. The
+// choice depends only on Seed and the observations.
+type RandomPerDay struct {
+	K    int
+	Seed int64
+}
+
+func (r RandomPerDay) String() string {
+	return fmt.Sprintf("RandomPerDay(%d, seed %d)", r.K, r.Seed)
+}
+
+func (r RandomPerDay) Sample(obses []ObsRoadweather) []ObsRoadweather {
+	type key struct {
+		station string
+		day     time.Time
+	}
+	groups := make(map[key][]int)
+	keys := []key{}
+	for i, obs := range obses {
+		k := key{obs.Station, obs.RefTime.UTC().Truncate(24 * time.Hour)}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], i)
+	}
+
+	rank := make([]uint64, len(obses))
+	for i, obs := range obses {
+		rank[i] = seededHash(r.Seed, obs.Station, obs.RefTime)
+	}
+
+	keep := make([]bool, len(obses))
+	for _, k := range keys {
+		idx := groups[k]
+		sort.SliceStable(idx, func(i, j int) bool {
+			return rank[idx[i]] < rank[idx[j]]
+		})
+		for n := 0; n < len(idx) && n < r.K; n++ {
+			keep[idx[n]] = true
+		}
+	}
+
+	kept := []ObsRoadweather{}
+	for i, obs := range obses {
+		if keep[i] {
+			kept = append(kept, obs)
+		}
+	}
+	return kept
+}
+
+// Rate keeps about the fraction p of the observations, reproducibly for seed.
+func Rate(p float64, seed int64) Sampler {
+	return described{SamplerFunc(func(obs ObsRoadweather) bool {
+		return float64(seededHash(seed, obs.Station, obs.RefTime)) < p*float64(^uint64(0))
+	}), fmt.Sprintf("Rate(%g, seed %d)", p, seed), seed}
+}
+
+// PerClass samples each class with its own sampler, the classes not in
+// samplers with def. A nil def keeps them all.
+func PerClass(samplers map[int]Sampler, def Sampler) Sampler {
+	return perClass{samplers, def}
+}
+
+type perClass struct {
+	samplers map[int]Sampler
+	def      Sampler
+}
+
+func (p perClass) String() string {
+	classes := maps.Keys(p.samplers)
+	sort.Ints(classes)
+	each := make([]string, len(classes))
+	for i, c := range classes {
+		each[i] = fmt.Sprintf("%d: %s", c, describeSampler(p.samplers[c]))
+	}
+	return fmt.Sprintf("PerClass({%s}, %s)", strings.Join(each, ", "), describeSampler(p.def))
+}
+
+func (p perClass) Sample(obses []ObsRoadweather) []ObsRoadweather {
+	byClass := make(map[int][]ObsRoadweather)
+	classes := []int{}
+	for _, obs := range obses {
+		if _, ok := byClass[obs.Class]; !ok {
+			classes = append(classes, obs.Class)
+		}
+		byClass[obs.Class] = append(byClass[obs.Class], obs)
+	}
+
+	kept := []ObsRoadweather{}
+	for _, class := range classes {
+		s, ok := p.samplers[class]
+		if !ok {
+			s = p.def
+		}
+		if s == nil {
+			kept = append(kept, byClass[class]...)
+			continue
+		}
+		kept = append(kept, s.Sample(byClass[class])...)
+	}
+	return kept
+}
+
+// Chain applies the samplers in order.
+func Chain(samplers ...Sampler) Sampler {
+	return chain(samplers)
+}
+
+type chain []Sampler
+
+func (c chain) String() string {
+	each := make([]string, len(c))
+	for i, s := range c {
+		each[i] = describeSampler(s)
+	}
+	return fmt.Sprintf("Chain(%s)", strings.Join(each, ", "))
+}
+
+func (c chain) Sample(obses []ObsRoadweather) []ObsRoadweather {
+	for _, s := range c {
+		obses = s.Sample(obses)
+	}
+	return obses
+}
+
+var (
+	hourly    = described{SamplerFunc(func(obs ObsRoadweather) bool { return obs.RefTime.Minute() == 0 }), "Hourly", 0}
+	sixHourly = FixedHours(0, 6, 12, 18)
+)
+
+// The samplers used by the GetDataFromFrost functions. Change them to change
+// the composition of the datasets, e.g. SamplerLabelApp to
+// Daylight{MinElevation: 5} for camera images with light.
+var (
+	Sampler3        Sampler = sixHourly
+	Sampler4        Sampler = sixHourly
+	Sampler6        Sampler = PerClass(map[int]Sampler{Dry6: sixHourly}, hourly) // Downsample Dry
+	Sampler8        Sampler = PerClass(map[int]Sampler{DryE: sixHourly}, hourly) // Downsample Dry
+	SamplerLabelApp Sampler = sixHourly
+)
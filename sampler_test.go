@@ -0,0 +1,180 @@
+package frostclient
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// series returns an observation of class every step from start for n steps
+// at each station.
+func series(start time.Time, step time.Duration, n int, class int, stations ...string) []ObsRoadweather {
+	obses := []ObsRoadweather{}
+	for _, st := range stations {
+		for i := 0; i < n; i++ {
+			obses = append(obses, ObsRoadweather{RefTime: start.Add(time.Duration(i) * step), Station: st, Class: class, Present: AllPresent})
+		}
+	}
+	return obses
+}
+
+func times(obses []ObsRoadweather) []string {
+	ts := []string{}
+	for _, obs := range obses {
+		ts = append(ts, obs.RefTime.UTC().Format("15:04"))
+	}
+	return ts
+}
+
+func TestEveryNMinutes(t *testing.T) {
+	day := series(time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), 10*time.Minute, 144, DryE, "SN1:0")
+	tests := []struct {
+		n    int
+		kept int
+		ok   bool
+	}{
+		{10, 144, true},
+		{30, 48, true},
+		{60, 24, true},
+		{360, 4, true},
+		{90, 16, true},
+		{0, 0, false},
+		{-10, 0, false},
+	}
+	for _, tt := range tests {
+		s, err := EveryNMinutes(tt.n)
+		if (err == nil) != tt.ok {
+			t.Errorf("EveryNMinutes(%d) error %v, want ok %v", tt.n, err, tt.ok)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := len(s.Sample(day)); got != tt.kept {
+			t.Errorf("EveryNMinutes(%d) kept %d, want %d", tt.n, got, tt.kept)
+		}
+	}
+}
+
+func TestFixedHours(t *testing.T) {
+	day := series(time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), 10*time.Minute, 144, DryE, "SN1:0")
+	if got, want := times(FixedHours(0, 12).Sample(day)), []string{"00:00", "12:00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FixedHours(0, 12) kept %v, want %v", got, want)
+	}
+	// Noon in Oslo is 11:00 UTC in winter and 10:00 UTC in summer
+	winter := series(time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), time.Hour, 24, DryE, "SN1:0")
+	summer := series(time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC), time.Hour, 24, DryE, "SN1:0")
+	if got := times(FixedHoursIn(Oslo, 12).Sample(winter)); !reflect.DeepEqual(got, []string{"11:00"}) {
+		t.Errorf("winter noon in Oslo at %v UTC", got)
+	}
+	if got := times(FixedHoursIn(Oslo, 12).Sample(summer)); !reflect.DeepEqual(got, []string{"10:00"}) {
+		t.Errorf("summer noon in Oslo at %v UTC", got)
+	}
+}
+
+func TestRandomPerDay(t *testing.T) {
+	obses := series(time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), 10*time.Minute, 288, DryE, "SN1:0", "SN2:0")
+	s := RandomPerDay{K: 3, Seed: 42}
+	kept := s.Sample(obses)
+	perDay := make(map[string]int)
+	for _, obs := range kept {
+		perDay[obs.Station+obs.RefTime.Format(" 2006-01-02")]++
+	}
+	want := map[string]int{"SN1:0 2023-02-10": 3, "SN1:0 2023-02-11": 3, "SN2:0 2023-02-10": 3, "SN2:0 2023-02-11": 3}
+	if !reflect.DeepEqual(perDay, want) {
+		t.Errorf("kept per day %v, want %v", perDay, want)
+	}
+
+	// The choice for a station does not depend on the other stations
+	alone := s.Sample(obses[288:])
+	if !reflect.DeepEqual(times(alone), times(kept[6:])) {
+		t.Errorf("SN2:0 alone kept %v, with SN1:0 %v", times(alone), times(kept[6:]))
+	}
+	if other := (RandomPerDay{K: 3, Seed: 43}).Sample(obses); reflect.DeepEqual(times(other), times(kept)) {
+		t.Errorf("seeds 42 and 43 kept the same observations")
+	}
+}
+
+func TestRate(t *testing.T) {
+	obses := series(time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), 10*time.Minute, 1440, DryE, "SN1:0", "SN2:0")
+	for _, p := range []float64{0, 0.1, 0.5, 1} {
+		n := len(Rate(p, 1).Sample(obses))
+		if got := float64(n) / float64(len(obses)); got < p-0.03 || got > p+0.03 {
+			t.Errorf("Rate(%g) kept %.3f", p, got)
+		}
+	}
+	if a, b := Rate(0.5, 1).Sample(obses), Rate(0.5, 1).Sample(obses); !reflect.DeepEqual(a, b) {
+		t.Errorf("Rate is not reproducible")
+	}
+}
+
+func TestPerClassAndChain(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	obses := append(series(start, 30*time.Minute, 48, DryE, "SN1:0"), series(start, 30*time.Minute, 48, IceE, "SN2:0")...)
+
+	s := PerClass(map[int]Sampler{DryE: FixedHours(0, 6, 12, 18)}, nil)
+	counts := make(map[int]int)
+	for _, obs := range s.Sample(obses) {
+		counts[obs.Class]++
+	}
+	if want := map[int]int{DryE: 4, IceE: 48}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("PerClass kept %v, want %v", counts, want)
+	}
+
+	hourly, _ := EveryNMinutes(60)
+	chained := Chain(hourly, PerClass(map[int]Sampler{DryE: FixedHours(6)}, All))
+	counts = make(map[int]int)
+	for _, obs := range chained.Sample(obses) {
+		counts[obs.Class]++
+	}
+	if want := map[int]int{DryE: 1, IceE: 24}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("Chain kept %v, want %v", counts, want)
+	}
+}
+
+// TestDatasetSamplerParity checks the samplers of the GetDataFromFrost
+// functions against the hour filters they had before the samplers.
+func TestDatasetSamplerParity(t *testing.T) {
+	sixHourly := func(t time.Time) bool {
+		h := t.UTC().Hour()
+		return t.UTC().Minute() == 0 && (h == 0 || h == 6 || h == 12 || h == 18)
+	}
+	wholeHour := func(t time.Time) bool { return t.UTC().Minute() == 0 }
+	tests := []struct {
+		name    string
+		sampler Sampler
+		dry     int
+		old     func(t time.Time, class int) bool
+	}{
+		{"3 classes", Sampler3, Dry, func(t time.Time, class int) bool { return sixHourly(t) }},
+		{"4 classes", Sampler4, Dry4, func(t time.Time, class int) bool { return sixHourly(t) }},
+		{"label app", SamplerLabelApp, DryE, func(t time.Time, class int) bool { return sixHourly(t) }},
+		{"6 classes", Sampler6, Dry6, func(t time.Time, class int) bool {
+			if class == Dry6 {
+				return sixHourly(t)
+			}
+			return wholeHour(t)
+		}},
+		{"8 classes", Sampler8, DryE, func(t time.Time, class int) bool {
+			if class == DryE {
+				return sixHourly(t)
+			}
+			return wholeHour(t)
+		}},
+	}
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		for _, class := range []int{tt.dry, tt.dry + 1, tt.dry + 2} {
+			obses := series(start, 10*time.Minute, 144, class, "SN1:0")
+			want := []ObsRoadweather{}
+			for _, obs := range obses {
+				if tt.old(obs.RefTime, class) {
+					want = append(want, obs)
+				}
+			}
+			if got := tt.sampler.Sample(obses); !reflect.DeepEqual(times(got), times(want)) {
+				t.Errorf("%s class %d: kept %v, want %v", tt.name, class, times(got), times(want))
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package frostclient
+
+import "fmt"
+
+// Sensor identifies the timeseries an observation value came from.
+type Sensor struct {
+	TimeSeriesID        int
+	PerformanceCategory string
+	ExposureCategory    string
+	QualityCode         int
+}
+
+func (s Sensor) String() string {
+	return fmt.Sprintf("%d/%s/%s/q%d", s.TimeSeriesID, s.PerformanceCategory, s.ExposureCategory, s.QualityCode)
+}
+
+func sensorOf(o Observation) Sensor {
+	return Sensor{
+		TimeSeriesID:        o.TimeSeriesID,
+		PerformanceCategory: o.PerformanceCategory,
+		ExposureCategory:    o.ExposureCategory,
+		QualityCode:         o.QualityCode,
+	}
+}
+
+type SensorCriterion int
+
+const (
+	ByPerformanceCategory SensorCriterion = iota
+	ByExposureCategory
+	ByQualityCode  // lower is better
+	ByTimeSeriesID // lower is better
+)
+
+// SensorPriority decides which timeseries to use when a station reports the
+// same element from several sensors. The criteria in Order are compared in
+// turn. Categories are listed best first; unlisted ones rank after them.
+type SensorPriority struct {
+	Order                 []SensorCriterion
+	PerformanceCategories []string
+	ExposureCategories    []string
+}
+
+// DefaultSensorPriority is used by the GetDataFromFrost functions.
+var DefaultSensorPriority = SensorPriority{
+	Order:                 []SensorCriterion{ByPerformanceCategory, ByExposureCategory, ByQualityCode, ByTimeSeriesID},
+	PerformanceCategories: []string{"A", "B", "C", "D", "E"},
+	ExposureCategories:    []string{"1", "2", "3", "4", "5"},
+}
+
+func rank(values []string, v string) int {
+	for i := range values {
+		if values[i] == v {
+			return i
+		}
+	}
+	return len(values)
+}
+
+// qualityRank ranks missing quality codes after all others.
+func qualityRank(code int) int {
+	if code == QualityMissing {
+		return int(^uint(0) >> 1)
+	}
+	return code
+}
+
+// Better reports whether a has higher priority than b.
+func (p SensorPriority) Better(a, b Observation) bool {
+	for _, c := range p.Order {
+		var ra, rb int
+		switch c {
+		case ByPerformanceCategory:
+			ra, rb = rank(p.PerformanceCategories, a.PerformanceCategory), rank(p.PerformanceCategories, b.PerformanceCategory)
+		case ByExposureCategory:
+			ra, rb = rank(p.ExposureCategories, a.ExposureCategory), rank(p.ExposureCategories, b.ExposureCategory)
+		case ByQualityCode:
+			ra, rb = qualityRank(a.QualityCode), qualityRank(b.QualityCode)
+		case ByTimeSeriesID:
+			ra, rb = a.TimeSeriesID, b.TimeSeriesID
+		}
+		if ra != rb {
+			return ra < rb
+		}
+	}
+	return false
+}
+
+// Merge returns the highest priority observation of each element, in the
+// order the elements first appear.
+func (p SensorPriority) Merge(obses []Observation) []Observation {
+	merged := []Observation{}
+	index := make(map[string]int)
+	for _, o := range obses {
+		i, ok := index[o.ElementID]
+		if !ok {
+			index[o.ElementID] = len(merged)
+			merged = append(merged, o)
+			continue
+		}
+		if p.Better(o, merged[i]) {
+			merged[i] = o
+		}
+	}
+	return merged
+}
+
+// parseRoadweather drops the observations of at rejected by quality, merges
+// the remaining sensors by priority and returns the thicknesses
+// in mm, with the sensor each value came from. Elements without an accepted
+// value are left out of Present.
+func parseRoadweather(at ObservationsAtRefTime, units *UnitValidator, quality *QualityFilter, priority SensorPriority) ObsRoadweather {
+	obs := ObsRoadweather{}
+	obs.Station = at.SourceID
+	obs.FrostID = at.SourceID
+	obs.RefTime = at.ReferenceTime.UTC()
+
+	observations := priority.Merge(quality.Filter(at.Observations))
+	for o := 0; o < len(observations); o++ {
+		value, ok := units.ToMM(observations[o])
+		if !ok {
+			continue
+		}
+		switch observations[o].ElementID {
+		case "road_ice_thickness":
+			obs.IceThickness = value
+			obs.IceSensor = sensorOf(observations[o])
+			obs.Present |= IcePresent
+		case "road_water_film_thickness":
+			obs.WaterFilmThickness = value
+			obs.WaterFilmSensor = sensorOf(observations[o])
+			obs.Present |= WaterFilmPresent
+		case "road_snow_thickness":
+			obs.SnowThickness = value
+			obs.SnowSensor = sensorOf(observations[o])
+			obs.Present |= SnowPresent
+		}
+	}
+	return obs
+}
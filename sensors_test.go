@@ -0,0 +1,50 @@
+package frostclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSensorPriorityBetter(t *testing.T) {
+	obs := func(ts int, perf, exp string, code int) Observation {
+		return Observation{ElementID: "road_ice_thickness", TimeSeriesID: ts, PerformanceCategory: perf, ExposureCategory: exp, QualityCode: code}
+	}
+	tests := []struct {
+		name string
+		p    SensorPriority
+		a, b Observation
+		want bool
+	}{
+		{"better performance", DefaultSensorPriority, obs(1, "A", "3", 0), obs(0, "C", "1", 0), true},
+		{"worse performance", DefaultSensorPriority, obs(0, "C", "1", 0), obs(1, "A", "3", 0), false},
+		{"unlisted performance ranks last", DefaultSensorPriority, obs(0, "X", "1", 0), obs(1, "E", "1", 0), false},
+		{"same performance, better exposure", DefaultSensorPriority, obs(1, "C", "1", 0), obs(0, "C", "2", 0), true},
+		{"then lower quality code", DefaultSensorPriority, obs(1, "C", "2", 0), obs(0, "C", "2", 2), true},
+		{"missing quality code ranks last", DefaultSensorPriority, obs(0, "C", "2", QualityMissing), obs(1, "C", "2", 7), false},
+		{"then lower timeseries", DefaultSensorPriority, obs(0, "C", "2", 0), obs(1, "C", "2", 0), true},
+		{"equal", DefaultSensorPriority, obs(0, "C", "2", 0), obs(0, "C", "2", 0), false},
+		{"quality first", SensorPriority{Order: []SensorCriterion{ByQualityCode, ByPerformanceCategory}, PerformanceCategories: []string{"A", "C"}},
+			obs(0, "C", "2", 0), obs(0, "A", "2", 1), true},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Better(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Better = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSensorPriorityMerge(t *testing.T) {
+	obses := []Observation{
+		{ElementID: "road_snow_thickness", TimeSeriesID: 0, PerformanceCategory: "D", Value: 1},
+		{ElementID: "road_ice_thickness", TimeSeriesID: 0, PerformanceCategory: "C", Value: 2},
+		{ElementID: "road_snow_thickness", TimeSeriesID: 1, PerformanceCategory: "B", Value: 3},
+		{ElementID: "road_ice_thickness", TimeSeriesID: 1, PerformanceCategory: "C", Value: 4},
+	}
+	want := []Observation{obses[2], obses[1]}
+	if got := DefaultSensorPriority.Merge(obses); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %+v, want %+v", got, want)
+	}
+	if got := DefaultSensorPriority.Merge(nil); len(got) != 0 {
+		t.Errorf("Merge(nil) = %+v, want none", got)
+	}
+}
@@ -0,0 +1,171 @@
+package frostclient
+
+import (
+	"sort"
+	"time"
+)
+
+// Hysteresis makes an element count as present once its thickness in mm
+// exceeds Enter, and as absent again only when it falls to Exit or below.
+type Hysteresis struct {
+	Enter float32
+	Exit  float32
+}
+
+// Smoothing post-processes the classes of each station's time series so
+// they change no more often than a camera image would. The steps run in
+// order hysteresis, majority vote, minimum duration, and the zero value of
+// each turns it off.
+type Smoothing struct {
+	// Hysteresis thresholds by element, ObsWater, ObsSnow or ObsIce.
+	// Observations are reclassified with the elements absent below them.
+	Hysteresis map[int]Hysteresis
+	// Window is the number of observations, centred on each, voting for
+	// its class. Ties keep the class.
+	Window int
+	// MinDuration is how long a class must last to be kept. Shorter runs
+	// take the class before them, or after them at the start of the series.
+	MinDuration time.Duration
+	// MaxGap splits a station's series where observations are further
+	// apart, 0 => never.
+	MaxGap time.Duration
+}
+
+// Apply returns obses with smoothed classes, sorted by station and time.
+// scheme is used to reclassify when Hysteresis is set.
+func (s Smoothing) Apply(scheme Scheme, obses []ObsRoadweather) []ObsRoadweather {
+	sorted := make([]ObsRoadweather, len(obses))
+	copy(sorted, obses)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Station != sorted[j].Station {
+			return sorted[i].Station < sorted[j].Station
+		}
+		if sorted[i].CamID != sorted[j].CamID {
+			return sorted[i].CamID < sorted[j].CamID
+		}
+		return sorted[i].RefTime.Before(sorted[j].RefTime)
+	})
+
+	start := 0
+	for i := 1; i <= len(sorted); i++ {
+		if i < len(sorted) && sorted[i].Station == sorted[start].Station && sorted[i].CamID == sorted[start].CamID &&
+			(s.MaxGap == 0 || sorted[i].RefTime.Sub(sorted[i-1].RefTime) <= s.MaxGap) {
+			continue
+		}
+		series := sorted[start:i]
+		s.hysteresis(scheme, series)
+		s.vote(series)
+		s.minDuration(series)
+		start = i
+	}
+	return sorted
+}
+
+func (s Smoothing) hysteresis(scheme Scheme, series []ObsRoadweather) {
+	if len(s.Hysteresis) == 0 {
+		return
+	}
+	present := make(map[int]bool)
+	for i := range series {
+		values := map[int]*float32{
+			ObsWater: &series[i].WaterFilmThickness,
+			ObsSnow:  &series[i].SnowThickness,
+			ObsIce:   &series[i].IceThickness,
+		}
+		effective := map[int]float32{}
+		for elm, v := range values {
+			effective[elm] = *v
+			h, ok := s.Hysteresis[elm]
+			if !ok {
+				continue
+			}
+			if present[elm] {
+				present[elm] = *v > h.Exit
+			} else {
+				present[elm] = *v > h.Enter
+			}
+			if !present[elm] {
+				effective[elm] = 0
+			}
+		}
+		series[i].Class = scheme.classify(effective[ObsIce], effective[ObsWater], effective[ObsSnow])
+	}
+}
+
+func (s Smoothing) vote(series []ObsRoadweather) {
+	if s.Window < 2 {
+		return
+	}
+	classes := make([]int, len(series))
+	for i := range series {
+		classes[i] = series[i].Class
+	}
+	half := s.Window / 2
+	for i := range series {
+		votes := make(map[int]int)
+		for j := i - half; j <= i+half; j++ {
+			if j >= 0 && j < len(classes) {
+				votes[classes[j]]++
+			}
+		}
+		best := classes[i]
+		for class, n := range votes {
+			// On a tie the class stays, else the lowest class wins
+			if n > votes[best] || (n == votes[best] && best != classes[i] && class < best) {
+				best = class
+			}
+		}
+		series[i].Class = best
+	}
+}
+
+func (s Smoothing) minDuration(series []ObsRoadweather) {
+	if s.MinDuration == 0 || len(series) == 0 {
+		return
+	}
+	type run struct{ start, end int } // [start, end)
+	runs := []run{}
+	for i := 0; i < len(series); {
+		j := i + 1
+		for j < len(series) && series[j].Class == series[i].Class {
+			j++
+		}
+		runs = append(runs, run{i, j})
+		i = j
+	}
+	if len(runs) == 1 {
+		return
+	}
+
+	duration := func(r run) time.Duration {
+		if r.end < len(series) {
+			return series[r.end].RefTime.Sub(series[r.start].RefTime)
+		}
+		return series[r.end-1].RefTime.Sub(series[r.start].RefTime)
+	}
+
+	prev := -1 // index of the last kept run
+	for k, r := range runs {
+		if duration(r) >= s.MinDuration {
+			prev = k
+			continue
+		}
+		class := -1
+		if prev >= 0 {
+			class = series[runs[prev].start].Class
+		} else {
+			for _, next := range runs[k+1:] {
+				if duration(next) >= s.MinDuration {
+					class = series[next.start].Class
+					break
+				}
+			}
+		}
+		if class < 0 {
+			continue // No run lasts long enough, leave as is
+		}
+		for i := r.start; i < r.end; i++ {
+			series[i].Class = class
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package frostclient
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// classSeries returns observations of station ten minutes apart with classes.
+func classSeries(station string, start time.Time, classes ...int) []ObsRoadweather {
+	obses := []ObsRoadweather{}
+	for i, c := range classes {
+		obses = append(obses, ObsRoadweather{RefTime: start.Add(time.Duration(i) * 10 * time.Minute), Station: station, Class: c, Present: AllPresent})
+	}
+	return obses
+}
+
+func classes(obses []ObsRoadweather) []int {
+	cs := []int{}
+	for _, obs := range obses {
+		cs = append(cs, obs.Class)
+	}
+	return cs
+}
+
+func TestSmoothingSteps(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		s       Smoothing
+		classes []int
+		want    []int
+	}{
+		{"off", Smoothing{}, []int{0, 1, 0, 1}, []int{0, 1, 0, 1}},
+		{"vote", Smoothing{Window: 3}, []int{0, 0, 1, 0, 0, 1, 1}, []int{0, 0, 0, 0, 0, 1, 1}},
+		{"vote tie keeps class", Smoothing{Window: 3}, []int{0, 1}, []int{0, 1}},
+		{"vote three-way tie keeps class", Smoothing{Window: 3}, []int{2, 3, 1}, []int{2, 3, 1}},
+		{"vote tie of others lowest wins", Smoothing{Window: 5}, []int{2, 2, 0, 1, 1}, []int{2, 2, 1, 1, 1}},
+		{"vote window 5", Smoothing{Window: 5}, []int{0, 1, 1, 0, 0, 1, 0}, []int{1, 1, 0, 1, 0, 0, 0}},
+		{"short run takes class before", Smoothing{MinDuration: 30 * time.Minute}, []int{0, 0, 0, 1, 1, 0, 0, 0}, []int{0, 0, 0, 0, 0, 0, 0, 0}},
+		{"long run kept", Smoothing{MinDuration: 30 * time.Minute}, []int{0, 0, 0, 1, 1, 1, 0, 0, 0, 0}, []int{0, 0, 0, 1, 1, 1, 0, 0, 0, 0}},
+		{"last run ends at its last observation", Smoothing{MinDuration: 30 * time.Minute}, []int{0, 0, 0, 1, 1, 1, 0, 0, 0}, []int{0, 0, 0, 1, 1, 1, 1, 1, 1}},
+		{"short first run takes class after", Smoothing{MinDuration: 30 * time.Minute}, []int{1, 0, 0, 0, 0}, []int{0, 0, 0, 0, 0}},
+		{"no run long enough", Smoothing{MinDuration: time.Hour}, []int{1, 0, 1, 0}, []int{1, 0, 1, 0}},
+		{"single run", Smoothing{MinDuration: time.Hour}, []int{1, 1}, []int{1, 1}},
+	}
+	for _, tt := range tests {
+		got := classes(tt.s.Apply(Scheme8, classSeries("SN1:0", start, tt.classes...)))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: classes %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSmoothingHysteresis(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	water := []float32{0, 0.15, 0.3, 0.15, 0.05, 0.15, 0.3}
+	obses := []ObsRoadweather{}
+	for i, w := range water {
+		obs := ObsRoadweather{RefTime: start.Add(time.Duration(i) * 10 * time.Minute), Station: "SN1:0", WaterFilmThickness: w, Present: AllPresent}
+		obs.Class, _ = Scheme8.Classify(obs)
+		obses = append(obses, obs)
+	}
+	tests := []struct {
+		name string
+		s    Smoothing
+		want []int
+	}{
+		{"off", Smoothing{}, []int{DryE, WetE, WetE, WetE, WetE, WetE, WetE}},
+		{"water", Smoothing{Hysteresis: map[int]Hysteresis{ObsWater: {Enter: 0.2, Exit: 0.1}}}, []int{DryE, DryE, WetE, WetE, DryE, DryE, WetE}},
+		{"other element", Smoothing{Hysteresis: map[int]Hysteresis{ObsIce: {Enter: 0.2, Exit: 0.1}}}, []int{DryE, WetE, WetE, WetE, WetE, WetE, WetE}},
+	}
+	for _, tt := range tests {
+		if got := classes(tt.s.Apply(Scheme8, obses)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: classes %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if obses[1].Class != WetE || obses[1].WaterFilmThickness != 0.15 {
+		t.Errorf("Apply changed its input: %+v", obses[1])
+	}
+}
+
+func TestSmoothingSeries(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	// Interleaved stations are smoothed separately and returned sorted
+	obses := append(classSeries("SN2:0", start, 1, 0, 1), classSeries("SN1:0", start, 0, 1, 0)...)
+	obses[0], obses[4] = obses[4], obses[0]
+	got := Smoothing{Window: 3}.Apply(Scheme8, obses)
+	if want := []int{0, 0, 0, 1, 1, 1}; !reflect.DeepEqual(classes(got), want) {
+		t.Errorf("classes %v, want %v", classes(got), want)
+	}
+	for i, st := range []string{"SN1:0", "SN1:0", "SN1:0", "SN2:0", "SN2:0", "SN2:0"} {
+		if got[i].Station != st || (i > 0 && got[i].Station == got[i-1].Station && !got[i].RefTime.After(got[i-1].RefTime)) {
+			t.Errorf("not sorted by station and time: %d %s %s", i, got[i].Station, got[i].RefTime)
+		}
+	}
+
+	// MaxGap splits the series, so the votes do not reach across the gap
+	gapped := append(classSeries("SN1:0", start, 0, 0, 1), classSeries("SN1:0", start.Add(24*time.Hour), 0, 1, 1)...)
+	tests := []struct {
+		maxGap time.Duration
+		want   []int
+	}{
+		{0, []int{0, 0, 0, 1, 1, 1}},
+		{time.Hour, []int{0, 0, 1, 0, 1, 1}},
+	}
+	for _, tt := range tests {
+		s := Smoothing{Window: 3, MaxGap: tt.maxGap}
+		if got := classes(s.Apply(Scheme8, gapped)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MaxGap %s: classes %v, want %v", tt.maxGap, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package frostclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// DatasetStop ends the datasets of the GetDataFromFrost functions running
+// until now, midnight UTC today when zero. The stop is recorded in the
+// snapshot of the build, see Snapshot.Build.
+var DatasetStop time.Time
+
+func datasetStop() time.Time {
+	if DatasetStop.IsZero() {
+		return time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	return DatasetStop.UTC()
+}
+
+// LastSnapshot is the snapshot of the latest GetDataFromFrost build. Add the
+// files written from the dataset with AddOutput and Write it next to them.
+var LastSnapshot Snapshot
+
+// SnapshotPath is where the GetDataFromFrost functions write LastSnapshot
+// when set. The functions write no files, so it records no outputs and
+// Verify fails on it until they are added with AddOutput and it is written
+// again.
+var SnapshotPath string
+
+// FrostAPIVersion is the apiVersion of the latest observation response.
+var FrostAPIVersion string
+
+// SnapshotFile is an output of a dataset build. Path is relative to the
+// snapshot manifest.
+type SnapshotFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Snapshot records how a dataset was built and the hashes of its files.
+type Snapshot struct {
+	Created          time.Time        `json:"created"`
+	ClientVersion    string           `json:"clientVersion"`
+	FrostAPIVersion  string           `json:"frostApiVersion"`
+	Query            ObsQuery         `json:"query"`
+	QueryURL         string           `json:"queryUrl"`
+	Start            time.Time        `json:"start"`
+	Stop             time.Time        `json:"stop"`
+	Stations         map[string][]int `json:"stations"` // Frost source ID => camera IDs
+	Skip             []string         `json:"skip,omitempty"`
+	Scheme           string           `json:"scheme"`
+	Classes          map[int]string   `json:"classes"`
+	RefuseIncomplete bool             `json:"refuseIncomplete"`
+	QualityPolicy    QualityPolicy    `json:"qualityPolicy"`
+	SensorPriority   SensorPriority   `json:"sensorPriority"`
+	Smooth           *Smoothing       `json:"smooth,omitempty"`
+	SeriesQuality    *SeriesChecks    `json:"seriesQuality,omitempty"`
+	Sampler          string           `json:"sampler,omitempty"`
+	Seed             int64            `json:"seed"`
+	Outputs          []SnapshotFile   `json:"outputs"`
+}
+
+// NewSnapshot records a build of scheme from start to stop with q and the
+// current package configuration. The sources of q are sorted and its
+// reference time is the whole period. Set the Stations, Skip, Sampler and
+// Seed, and add the outputs with AddOutput.
+func NewSnapshot(scheme Scheme, q ObsQuery, start, stop time.Time) Snapshot {
+	q.Sources = append([]string{}, q.Sources...)
+	sort.Strings(q.Sources)
+	q.ReferenceTime = fmt.Sprintf("%s/%s", start.UTC().Format("2006-01-02T15:04Z"), stop.UTC().Format("2006-01-02T15:04Z"))
+	return Snapshot{
+		Created:          time.Now().UTC(),
+		ClientVersion:    Version,
+		FrostAPIVersion:  FrostAPIVersion,
+		Query:            q,
+		QueryURL:         q.URL(),
+		Start:            start.UTC(),
+		Stop:             stop.UTC(),
+		Stations:         map[string][]int{},
+		Scheme:           scheme.Name,
+		Classes:          scheme.Classes,
+		RefuseIncomplete: RefuseIncomplete,
+		QualityPolicy:    DefaultQualityPolicy,
+		SensorPriority:   DefaultSensorPriority,
+		Outputs:          []SnapshotFile{},
+	}
+}
+
+// Build returns the build that rebuilds s with the GetDataFromFrost
+// function that made it: the same stations and period, classified, checked,
+// smoothed and sampled the same way. Its sampler must be set up as recorded,
+// the build fails otherwise. The package configuration is left as it is.
+// Values Frost has corrected since are not restored, compare the outputs
+// with Verify.
+func (s Snapshot) Build() Build {
+	return Build{Smooth: s.Smooth, SeriesQuality: s.SeriesQuality, Rebuild: &s}
+}
+
+// rebuilds returns an error if a build by b of scheme by sampler from start
+// to stop is not the one recorded in s. Samplers without a description, see
+// Named, can not be told apart and are refused.
+func (s Snapshot) rebuilds(b Build, scheme Scheme, sampler Sampler, start, stop time.Time) error {
+	if scheme.Name != s.Scheme {
+		return fmt.Errorf("scheme %s, snapshot has %s", scheme.Name, s.Scheme)
+	}
+	if !samplerDescribed(sampler) {
+		return fmt.Errorf("sampler %s has no description, name it with Named", describeSampler(sampler))
+	}
+	if desc := describeSampler(sampler); desc != s.Sampler {
+		return fmt.Errorf("sampler %s, snapshot has %s", desc, s.Sampler)
+	}
+	if !reflect.DeepEqual(b.Smooth, s.Smooth) || !reflect.DeepEqual(b.SeriesQuality, s.SeriesQuality) {
+		return fmt.Errorf("smoothing or series checks differ from the snapshot")
+	}
+	if !start.Equal(s.Start) || !stop.Equal(s.Stop) {
+		return fmt.Errorf("period %s/%s, snapshot has %s/%s", start.Format(time.RFC3339), stop.Format(time.RFC3339),
+			s.Start.Format(time.RFC3339), s.Stop.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AddOutput hashes the files in paths, given relative to dir, the directory
+// of the snapshot manifest.
+func (s *Snapshot) AddOutput(dir string, paths ...string) error {
+	for _, p := range paths {
+		size, sum, err := hashFile(filepath.Join(dir, p))
+		if err != nil {
+			return fmt.Errorf("hashing %s: %v", p, err)
+		}
+		s.Outputs = append(s.Outputs, SnapshotFile{Path: filepath.ToSlash(p), Size: size, SHA256: sum})
+	}
+	return nil
+}
+
+// Write writes the snapshot manifest to path.
+func (s Snapshot) Write(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("os.WriteFile(%s): %v", path, err)
+	}
+	return nil
+}
+
+func LoadSnapshot(path string) (Snapshot, error) {
+	s := Snapshot{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return s, fmt.Errorf("os.ReadFile(%s): %v", path, err)
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return s, fmt.Errorf("decoding snapshot %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Verify checks the outputs in dir against their recorded size and hash and
+// returns a problem per output that does not match. A snapshot without
+// outputs has nothing to check and is a problem too.
+func (s Snapshot) Verify(dir string) []error {
+	problems := []error{}
+	if len(s.Outputs) == 0 {
+		problems = append(problems, fmt.Errorf("snapshot records no outputs, add them with AddOutput"))
+	}
+	for _, o := range s.Outputs {
+		size, sum, err := hashFile(filepath.Join(dir, filepath.FromSlash(o.Path)))
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Errorf("%s: %v", o.Path, err))
+		case size != o.Size:
+			problems = append(problems, fmt.Errorf("%s: size %d, snapshot has %d", o.Path, size, o.Size))
+		case sum != o.SHA256:
+			problems = append(problems, fmt.Errorf("%s: sha256 %s, snapshot has %s", o.Path, sum, o.SHA256))
+		}
+	}
+	return problems
+}
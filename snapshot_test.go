@@ -0,0 +1,172 @@
+package frostclient
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(48 * time.Hour)
+	q := DefaultObsQuery
+	q.Sources = []string{"SN2:0", "SN10:0"}
+	s := NewSnapshot(Scheme8, q, start, stop)
+	if want := []string{"SN10:0", "SN2:0"}; !reflect.DeepEqual(s.Query.Sources, want) {
+		t.Errorf("sources %v, want %v", s.Query.Sources, want)
+	}
+	if q.Sources[0] != "SN2:0" {
+		t.Errorf("NewSnapshot sorted the sources of the query")
+	}
+	if s.Query.ReferenceTime != "2023-02-10T00:00Z/2023-02-12T00:00Z" || s.QueryURL != s.Query.URL() {
+		t.Errorf("reference time %s, URL %s", s.Query.ReferenceTime, s.QueryURL)
+	}
+	s.Stations = map[string][]int{"SN10:0": {3}, "SN2:0": {1, 2}}
+	s.Smooth = &Smoothing{Window: 3, MinDuration: 30 * time.Minute}
+	s.Sampler = describeSampler(Sampler8)
+	s.Seed = samplerSeed(Sampler8)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "dataset.csv"), []byte("a,b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddOutput(dir, "dataset.csv"); err != nil {
+		t.Fatalf("AddOutput: %v", err)
+	}
+	if err := s.AddOutput(dir, "missing.csv"); err == nil {
+		t.Errorf("AddOutput of a missing file succeeded")
+	}
+	path := filepath.Join(dir, "snapshot.json")
+	if err := s.Write(path); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	loaded, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("loaded\n%+v\nwant\n%+v", loaded, s)
+	}
+	if _, err := LoadSnapshot(filepath.Join(dir, "dataset.csv")); err == nil {
+		t.Errorf("LoadSnapshot of a CSV file succeeded")
+	}
+}
+
+func TestSnapshotVerify(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("train.csv", "path,label\na.jpg,0\n")
+	write("sub/val.csv", "path,label\nb.jpg,3\n")
+	write("test.csv", "path,label\n")
+	s := Snapshot{}
+	if err := s.AddOutput(dir, "train.csv", filepath.Join("sub", "val.csv"), "test.csv"); err != nil {
+		t.Fatalf("AddOutput: %v", err)
+	}
+	if s.Outputs[1].Path != "sub/val.csv" || s.Outputs[0].Size != 19 {
+		t.Errorf("outputs %+v", s.Outputs)
+	}
+	if problems := s.Verify(dir); len(problems) != 0 {
+		t.Errorf("Verify of the written files: %v", problems)
+	}
+
+	write("train.csv", "path,label\na.jpg,1\n") // same size
+	write("sub/val.csv", "path,label\n")
+	os.Remove(filepath.Join(dir, "test.csv"))
+	got := []string{}
+	for _, p := range s.Verify(dir) {
+		got = append(got, p.Error())
+	}
+	if len(got) != 3 || !strings.HasPrefix(got[0], "train.csv: sha256") ||
+		!strings.HasPrefix(got[1], "sub/val.csv: size 11, snapshot has 19") || !strings.HasPrefix(got[2], "test.csv:") {
+		t.Errorf("Verify of the changed files:\n%s", strings.Join(got, "\n"))
+	}
+
+	// A snapshot written by a build has no outputs to verify
+	if problems := (Snapshot{}).Verify(dir); len(problems) != 1 {
+		t.Errorf("Verify without outputs = %v, want a problem", problems)
+	}
+}
+
+func TestSnapshotRebuilds(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(24 * time.Hour)
+	custom := Daylight{Locate: func(string, time.Time) (float64, float64, bool) { return 60, 10, true }}
+	fn := SamplerFunc(func(obs ObsRoadweather) bool { return obs.CamID == 1 })
+	named := Named("camera 1", custom)
+	smooth := &Smoothing{Window: 3}
+	s8 := describeSampler(Sampler8)
+	// The undescribed samplers are recorded as they describe themselves,
+	// which would match any other sampler of their type
+	tests := []struct {
+		name     string
+		recorded string
+		sampler  Sampler
+		build    Build
+		scheme   Scheme
+		stop     time.Time
+		ok       bool
+	}{
+		{"same", s8, Sampler8, Build{Smooth: smooth}, Scheme8, stop, true},
+		{"other scheme", s8, Sampler8, Build{Smooth: smooth}, Scheme3, stop, false},
+		{"other sampler", s8, Sampler3, Build{Smooth: smooth}, Scheme8, stop, false},
+		{"other period", s8, Sampler8, Build{Smooth: smooth}, Scheme8, stop.Add(time.Hour), false},
+		{"other smoothing", s8, Sampler8, Build{Smooth: &Smoothing{Window: 5}}, Scheme8, stop, false},
+		{"no smoothing", s8, Sampler8, Build{}, Scheme8, stop, false},
+		{"sampler function", describeSampler(fn), fn, Build{Smooth: smooth}, Scheme8, stop, false},
+		{"sampler function in a chain", describeSampler(Chain(hourly, fn)), Chain(hourly, fn), Build{Smooth: smooth}, Scheme8, stop, false},
+		{"custom Locate", describeSampler(custom), custom, Build{Smooth: smooth}, Scheme8, stop, false},
+		{"named custom Locate", "camera 1", named, Build{Smooth: smooth}, Scheme8, stop, true},
+	}
+	for _, tt := range tests {
+		s := NewSnapshot(Scheme8, DefaultObsQuery, start, stop)
+		s.Smooth = &Smoothing{Window: 3}
+		s.Sampler = tt.recorded
+		err := s.rebuilds(tt.build, tt.scheme, tt.sampler, start, tt.stop)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: rebuilds = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestSnapshotBuild(t *testing.T) {
+	start := time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(24 * time.Hour)
+	q := DefaultObsQuery
+	q.Sources = []string{"SN1:0"}
+	q.TimeResolutions = []string{"PT1H"}
+	s := NewSnapshot(Scheme8, q, start, stop)
+	s.RefuseIncomplete = !RefuseIncomplete
+	s.QualityPolicy = QualityPolicy{MaxQualityCode: 7}
+	s.SensorPriority = SensorPriority{Order: []SensorCriterion{ByTimeSeriesID}}
+	s.SeriesQuality = &DefaultSeriesChecks
+
+	defaults := Build{}.settings()
+	b := s.Build()
+	if b.Rebuild == nil || b.SeriesQuality != s.SeriesQuality || b.Smooth != nil || !b.stop().Equal(stop) {
+		t.Errorf("Build = %+v", b)
+	}
+	want := buildSettings{
+		query:            ObsQuery{Elements: q.Elements, TimeOffsets: q.TimeOffsets, TimeResolutions: []string{"PT1H"}},
+		qualityPolicy:    s.QualityPolicy,
+		sensorPriority:   s.SensorPriority,
+		refuseIncomplete: s.RefuseIncomplete,
+	}
+	if got := b.settings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("settings\n%+v\nwant\n%+v", got, want)
+	}
+	// The package configuration is left as it is
+	if got := (Build{}).settings(); !reflect.DeepEqual(got, defaults) || got.refuseIncomplete != RefuseIncomplete {
+		t.Errorf("package settings changed to %+v", got)
+	}
+}
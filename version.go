@@ -0,0 +1,33 @@
+package frostclient
+
+import "runtime/debug"
+
+const modulePath = "github.com/metno/frostclient-roadweather"
+
+// Version is the release of this module a program was built with, like
+// "v0.3.1" from its go.mod, and is recorded in exported files. Builds of an
+// untagged checkout or with a replace directive get "(devel)".
+var Version = moduleVersion()
+
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	version := ""
+	if info.Main.Path == modulePath {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			version = dep.Version
+			if dep.Replace != nil {
+				version = dep.Replace.Version
+			}
+		}
+	}
+	if version == "" {
+		return "(devel)"
+	}
+	return version
+}